@@ -2,429 +2,280 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"flag"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
-)
-
-var hadError bool = false
-
-type TokenType int
-
-const (
-	// Single-character tokens.
-	LEFT_PAREN TokenType = iota
-	RIGHT_PAREN
-	LEFT_BRACE
-	RIGHT_BRACE
-	COMMA
-	DOT
-	MINUS
-	PLUS
-	SEMICOLON
-	SLASH
-	STAR
-
-	// One or two character tokens.
-	BANG
-	BANG_EQUAL
-	EQUAL
-	EQUAL_EQUAL
-	GREATER
-	GREATER_EQUAL
-	LESS
-	LESS_EQUAL
-
-	// Literals.
-	IDENTIFIER
-	STRING
-	NUMBER
-
-	// Keywords.
-	AND
-	CLASS
-	ELSE
-	FALSE
-	FUN
-	FOR
-	IF
-	NIL
-	OR
-	PRINT
-	RETURN
-	SUPER
-	THIS
-	TRUE
-	VAR
-	WHILE
+	"sort"
+	"time"
 
-	// EOF token
-	EOF
+	"github.com/codecrafters-io/interpreter-starter-go/lox"
 )
 
-var keywords = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"for":    FOR,
-	"fun":    FUN,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
-}
-
-type LoxLiteral interface {
-	RawPrint() string
-}
-
-type LoxString struct {
-	value string
-}
-
-func (s LoxString) RawPrint() string {
-	return fmt.Sprintf("%s", s.value)
-}
-
-type LoxNumber struct {
-	value float64
-}
-
-func (n LoxNumber) RawPrint() string {
-	if float64(int64(n.value)) == n.value {
-		return fmt.Sprintf("%.1f", n.value)
-	} else {
-		return fmt.Sprintf("%v", n.value)
-	}
-}
-
-type LoxEmptyLiteral struct{}
-
-func (e LoxEmptyLiteral) RawPrint() string {
-	return "null"
-}
-
-type Token struct {
-	_type   TokenType
-	lexeme  string
-	literal LoxLiteral
-	line    int
-}
-
-func (tok *Token) String() string {
-	return fmt.Sprintf("%s %s %s", tok._type, tok.lexeme, tok.literal.RawPrint())
-}
-
-type Scanner struct {
-	source  string
-	tokens  []Token
-	start   int
-	current int
-	line    int
-}
-
-func NewScanner(source string) Scanner {
-	return Scanner{
-		source: source,
-		line:   1,
-	}
-}
-
-func (scan *Scanner) isAtEnd() bool {
-	return scan.current >= len(scan.source)
-}
-
-func (scan *Scanner) ScanTokens() []Token {
-	for !scan.isAtEnd() {
-		scan.start = scan.current
-		scan.scanToken()
-	}
-	tok := Token{
-		_type:   EOF,
-		lexeme:  "",
-		literal: LoxEmptyLiteral{},
-		line:    scan.line,
-	}
-	scan.tokens = append(scan.tokens, tok)
-	return scan.tokens
-}
-
-func (scan *Scanner) scanToken() {
-	var char byte = scan.advance()
-	switch char {
-	case '(':
-		scan.addToken(LEFT_PAREN)
-	case ')':
-		scan.addToken(RIGHT_PAREN)
-	case '{':
-		scan.addToken(LEFT_BRACE)
-	case '}':
-		scan.addToken(RIGHT_BRACE)
-	case ',':
-		scan.addToken(COMMA)
-	case '.':
-		scan.addToken(DOT)
-	case '-':
-		scan.addToken(MINUS)
-	case '+':
-		scan.addToken(PLUS)
-	case ';':
-		scan.addToken(SEMICOLON)
-	case '*':
-		scan.addToken(STAR)
-	case '!':
-		if scan.match('=') {
-			scan.addToken(BANG_EQUAL)
-		} else {
-			scan.addToken(BANG)
-		}
-	case '=':
-		if scan.match('=') {
-			scan.addToken(EQUAL_EQUAL)
-		} else {
-			scan.addToken(EQUAL)
-		}
-	case '<':
-		if scan.match('=') {
-			scan.addToken(LESS_EQUAL)
-		} else {
-			scan.addToken(LESS)
-		}
-	case '>':
-		if scan.match('=') {
-			scan.addToken(GREATER_EQUAL)
-		} else {
-			scan.addToken(GREATER)
-		}
-	case '/':
-		if scan.match('/') {
-			for scan.peek() != '\n' && !scan.isAtEnd() {
-				scan.advance()
-			}
-		} else {
-			scan.addToken(SLASH)
-		}
-	case ' ', '\r', '\t':
-		// Do nothing, skip
-	case '\n':
-		scan.line++
-	case '"':
-		scan.parseString()
-	default:
-		if scan.isDigit(char) {
-			scan.number()
-		} else if scan.isAlpha(char) {
-			scan.identifier()
-		} else {
-			message := fmt.Sprintf("Unexpected character: %c", char)
-			LoxError(scan.line, message)
-		}
+func PrintTokens(interpreter *lox.Interpreter, source string) {
+	tokenize := interpreter.Tokenize
+	if keepComments {
+		tokenize = interpreter.TokenizeWithComments
 	}
-}
+	tokens, errors := tokenize(source)
+	interpreter.ReportScanErrors(errors)
 
-func (scan *Scanner) advance() byte {
-	ret := scan.source[scan.current]
-	scan.current++
-	return ret
-
-}
-
-func (scan *Scanner) peek() byte {
-	if scan.isAtEnd() {
-		return '\000' // Rune literals are three-digit octals
+	if offsets {
+		lox.WriteTokenTable(os.Stdout, tokens)
+		return
 	}
-
-	return scan.source[scan.current]
-}
-
-func (scan *Scanner) peekNext() byte {
-	if scan.current+1 > len(scan.source) {
-		return '\000'
+	for _, tok := range tokens {
+		fmt.Printf("%s\n", &tok)
 	}
-	return scan.source[scan.current+1]
 }
 
-func (scan *Scanner) match(expected byte) bool {
-	if scan.isAtEnd() {
-		return false
-	}
-
-	if scan.source[scan.current] != expected {
-		return false
+// PrintExpression prints the syntax tree of source, which must be a single
+// expression, as an S-expression
+func PrintExpression(interpreter *lox.Interpreter, source string) {
+	tokens, errors := interpreter.Tokenize(source)
+	interpreter.ReportScanErrors(errors)
+	parser := interpreter.NewParser(tokens)
+	expr, parseErrors := parser.ParseExpression()
+	interpreter.ReportParseErrors(parseErrors)
+	if interpreter.HadError() {
+		return
 	}
-
-	scan.current++
-
-	return true
+	fmt.Println(lox.AstPrinter{}.Print(expr))
 }
 
-func (scan *Scanner) number() {
-	for scan.isDigit(scan.peek()) {
-		scan.advance()
+// PrintAST prints the syntax tree of source, as JSON or as a DOT graph
+func PrintAST(interpreter *lox.Interpreter, source string, dot bool) {
+	tokens, errors := interpreter.Tokenize(source)
+	interpreter.ReportScanErrors(errors)
+	parser := interpreter.NewParser(tokens)
+	statements, parseErrors := parser.Parse()
+	interpreter.ReportParseErrors(parseErrors)
+	if interpreter.HadError() {
+		return
 	}
 
-	if scan.peek() == '.' && scan.isDigit(scan.peekNext()) {
-		// Consume the "."
-		scan.advance()
-
-		for scan.isDigit(scan.peek()) {
-			scan.advance()
-		}
+	if dot {
+		fmt.Print(lox.DotAST(statements))
+		return
 	}
-	number, err := strconv.ParseFloat(scan.source[scan.start:scan.current], 64)
+	data, err := lox.MarshalAST(statements)
 	if err != nil {
-		//
+		fmt.Fprintf(os.Stderr, "Error encoding AST: %v\n", err)
+		os.Exit(1)
 	}
-	numberLiteral := LoxNumber{value: number}
-	scan.addTokenAndLiteral(NUMBER, numberLiteral)
-
+	fmt.Println(string(data))
 }
 
-func (scan *Scanner) identifier() {
-	for scan.isAlphaNumeric(scan.peek()) {
-		scan.advance()
-	}
-
-	text := scan.source[scan.start:scan.current]
-	_type, ok := keywords[text]
-	if !ok {
-		_type = IDENTIFIER
+// PrintFormatted prints the program reformatted, or reports its syntax
+// errors and prints nothing
+func PrintFormatted(interpreter *lox.Interpreter, source string) {
+	formatted, scanErrors, parseErrors := interpreter.Format(source)
+	interpreter.ReportScanErrors(scanErrors)
+	interpreter.ReportParseErrors(parseErrors)
+	if interpreter.HadError() {
+		return
 	}
-	scan.addToken(_type)
+	fmt.Print(formatted)
 }
 
-func (scan *Scanner) isDigit(char byte) bool {
-	return char >= '0' && char <= '9'
-}
-
-func (scan *Scanner) isAlpha(char byte) bool {
-	return (char >= 'a' && char <= 'z') ||
-		(char >= 'A' && char <= 'Z') ||
-		char == '_'
-}
-
-func (scan *Scanner) isAlphaNumeric(char byte) bool {
-	return scan.isAlpha(char) || scan.isDigit(char)
-}
-
-func (scan *Scanner) parseString() {
-	for scan.peek() != '"' && !scan.isAtEnd() {
-		if scan.peek() == '\n' {
-			scan.line++
-		}
-		scan.advance()
-	}
-
-	if scan.isAtEnd() {
-		LoxError(scan.line, "Unterminated string.")
+// PrintStats prints the count of each kind of node in the program, by name
+func PrintStats(interpreter *lox.Interpreter, source string) {
+	tokens, errors := interpreter.Tokenize(source)
+	interpreter.ReportScanErrors(errors)
+	parser := interpreter.NewParser(tokens)
+	statements, parseErrors := parser.Parse()
+	interpreter.ReportParseErrors(parseErrors)
+	if interpreter.HadError() {
 		return
 	}
 
-	// The closing "
-	scan.advance()
-
-	// Trim the surrounding quotes
-	literal := LoxString{
-		value: scan.source[scan.start+1 : scan.current-1],
+	stats := lox.Stats{}.Count(statements)
+	kinds := make([]string, 0, len(stats))
+	for kind := range stats {
+		kinds = append(kinds, kind)
 	}
-	scan.addTokenAndLiteral(STRING, literal)
-}
-
-func (scan *Scanner) addToken(_type TokenType) {
-	scan.addTokenAndLiteral(_type, LoxEmptyLiteral{})
-}
-
-func (scan *Scanner) addTokenAndLiteral(_type TokenType, literal LoxLiteral) {
-	text := scan.source[scan.start:scan.current]
-	tok := Token{
-		_type:   _type,
-		lexeme:  text,
-		literal: literal,
-		line:    scan.line,
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		fmt.Printf("%-14s %d\n", kind, stats[kind])
 	}
-	scan.tokens = append(scan.tokens, tok)
 }
 
-func Run(source string) {
-	// fmt.Printf("reading source '%s'\n", source)
-	scanner := NewScanner(source)
-	tokens := scanner.ScanTokens()
-
-	// For now, just print the Tokens
-	for _, tok := range tokens {
-		fmt.Printf("%s\n", &tok)
+// ReadFile reads the program at path, or from stdin if path is "-"
+func ReadFile(path string) string {
+	var fileContents []byte
+	var err error
+	if path == "-" {
+		fileContents, err = io.ReadAll(os.Stdin)
+	} else {
+		fileContents, err = os.ReadFile(path)
 	}
-}
-
-func RunFile(path string) {
-	fileContents, err := os.ReadFile(path)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
 		os.Exit(1)
 	}
-	Run(string(fileContents))
-
+	return string(fileContents)
 }
 
-func RunPrompt() {
-	reader := bufio.NewScanner(os.Stdin)
-	fmt.Print("> ")
+func RunFile(interpreter *lox.Interpreter, path string) int {
+	source := ReadFile(path)
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return interpreter.RunContext(ctx, source)
+	}
+	return interpreter.Run(source)
+}
+
+// RunPrompt runs the lines read from in one at a time, printing to out and
+// reporting errors to errs, until in ends or a line calls exit(). An error on
+// one line doesn't affect the next. It returns the code to exit with.
+func RunPrompt(in io.Reader, out io.Writer, errs io.Writer) int {
+	reader := bufio.NewScanner(in)
+	interpreter := lox.NewInterpreter()
+	interpreter.Out = out
+	interpreter.Err = errs
+	fmt.Fprint(out, "> ")
 	for reader.Scan() {
 		line := reader.Text()
-		Run(line)
-		fmt.Print("> ")
+		if code, exited := interpreter.RunLine(line); exited {
+			return code
+		}
+		fmt.Fprint(out, "> ")
 	}
-	fmt.Print("\nExit\n")
-
+	fmt.Fprint(out, "\nExit\n")
+	return lox.ExitOK
 }
 
-func LoxError(line int, message string) {
-	LoxReport(line, "", message)
+// commands lists each subcommand with a short description for usage
+var commands = []struct{ name, description string }{
+	{"tokenize", "print the tokens scanned from the file"},
+	{"parse", "print the syntax tree of a single expression"},
+	{"evaluate", "evaluate a single expression and print its value"},
+	{"ast", "print the syntax tree as JSON (--json) or a Graphviz DOT graph (--dot)"},
+	{"fmt", "print the program formatted canonically"},
+	{"stats", "print how many syntax tree nodes of each kind the program has"},
+	{"run", "run the program"},
 }
 
-func LoxReport(line int, where string, message string) {
-	fmt.Fprintf(os.Stderr, "[line %d] Error%s: %s\n", line, where, message)
-	hadError = true
+func isCommand(name string) bool {
+	for _, command := range commands {
+		if command.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isFile reports whether path names an existing regular file
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+var jsonOutput, dotOutput, looseConcat, strict, keepComments, offsets, warnUnused, fold bool
+var maxDepth, maxErrors int
+var timeout time.Duration
+
+func newFlagSet(command string) *flag.FlagSet {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.BoolVar(&warnUnused, "warn-unused", false, "warn about local variables that are never read")
+	flags.BoolVar(&jsonOutput, "json", false, "print the syntax tree as JSON (ast)")
+	flags.BoolVar(&dotOutput, "dot", false, "print the syntax tree as a Graphviz DOT graph (ast)")
+	flags.BoolVar(&looseConcat, "loose-concat", false, "let + concatenate a string with any value (run)")
+	flags.BoolVar(&strict, "strict", false, "make reading a variable before it is assigned an error (run)")
+	flags.IntVar(&maxDepth, "max-depth", lox.DefaultMaxCallDepth, "limit on calls in progress at once, 0 for none (run)")
+	flags.DurationVar(&timeout, "timeout", 0, "stop the program if it runs longer than this, e.g. 5s (run)")
+	flags.BoolVar(&fold, "fold", false, "fold constant subexpressions before running (run, evaluate)")
+	flags.IntVar(&maxErrors, "max-errors", lox.DefaultMaxErrors, "stop after reporting this many scan or parse errors, 0 for no limit")
+	flags.BoolVar(&keepComments, "comments", false, "print comments as COMMENT tokens (tokenize)")
+	flags.BoolVar(&offsets, "offsets", false, "print a table of tokens with their positions in the file (tokenize)")
+	flags.Usage = func() { usage(flags.Output(), flags) }
+	return flags
+}
+
+// newInterpreter returns an Interpreter set up as the flags ask
+func newInterpreter() *lox.Interpreter {
+	interpreter := lox.NewInterpreter()
+	interpreter.WarnUnused = warnUnused
+	interpreter.FoldConstants = fold
+	interpreter.MaxErrors = maxErrors
+	interpreter.LooseConcat = looseConcat
+	interpreter.Strict = strict
+	interpreter.MaxCallDepth = maxDepth
+	return interpreter
+}
+
+func usage(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage: ./your_program.sh <command> [flags] <filename>")
+	fmt.Fprintln(w, "       ./your_program.sh <filename> (same as run)")
+	fmt.Fprintln(w, "       ./your_program.sh            (interactive prompt)")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, command := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", command.name, command.description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "A filename of - reads the program from stdin.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	flags.SetOutput(w)
+	flags.PrintDefaults()
 }
 
 func main() {
 	// You can use print statements as follows for debugging, they'll be visible when running tests.
 	fmt.Fprintln(os.Stderr, "Logs from your program will appear here!")
-	// RunPrompt()
+	if len(os.Args) == 1 {
+		os.Exit(RunPrompt(os.Stdin, os.Stdout, os.Stderr))
+	}
 
-	if len(os.Args) < 3 {
-		fmt.Fprintln(os.Stderr, "Usage: ./your_program.sh tokenize <filename>")
+	command, args := os.Args[1], os.Args[2:]
+	if !isCommand(command) && isFile(command) {
+		// ./your_program.sh script.lox is shorthand for the run command
+		command, args = "run", os.Args[1:]
+	}
+	flags := newFlagSet(command)
+	switch {
+	case command == "-h" || command == "--help" || command == "help":
+		usage(os.Stdout, flags)
+		return
+	case !isCommand(command):
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", command)
+		usage(os.Stderr, flags)
 		os.Exit(1)
 	}
 
-	command := os.Args[1]
-
-	if command != "tokenize" {
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
+	flags.Parse(args)
+	if flags.NArg() < 1 {
+		usage(os.Stderr, flags)
 		os.Exit(1)
 	}
-
-	filename := os.Args[2]
-	RunFile(filename)
-	if hadError {
-		os.Exit(65)
+	filename := flags.Arg(0)
+	interpreter := newInterpreter()
+
+	switch command {
+	case "tokenize":
+		PrintTokens(interpreter, ReadFile(filename))
+	case "parse":
+		PrintExpression(interpreter, ReadFile(filename))
+	case "evaluate":
+		os.Exit(interpreter.RunExpression(ReadFile(filename)))
+	case "ast":
+		if jsonOutput == dotOutput {
+			fmt.Fprintln(os.Stderr, "Usage: ./your_program.sh ast <--json|--dot> <filename>")
+			os.Exit(1)
+		}
+		PrintAST(interpreter, ReadFile(filename), dotOutput)
+	case "fmt":
+		PrintFormatted(interpreter, ReadFile(filename))
+	case "stats":
+		PrintStats(interpreter, ReadFile(filename))
+	case "run":
+		os.Exit(RunFile(interpreter, filename))
 	}
-	// fileContents, err := os.ReadFile(filename)
-	// if err != nil {
-	// 	fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
-	// 	os.Exit(1)
-	// }
 
-	// if len(fileContents) > 0 {
-	// 	panic("Scanner not implemented")
-	// } else {
-	// 	fmt.Println("EOF  null") // Placeholder, remove this line when implementing the scanner
-	// }
+	if interpreter.HadError() {
+		os.Exit(lox.ExitSyntaxError)
+	}
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs main instead of the tests when runMain starts the test
+// binary as the interpreter
+func TestMain(m *testing.M) {
+	if os.Getenv("GOLOX_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs the interpreter with args, feeding it stdin, and returns what
+// it printed on stdout and stderr and its exit code
+func runMain(t *testing.T, stdin string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	var out, errs bytes.Buffer
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "GOLOX_RUN_MAIN=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = &out
+	cmd.Stderr = &errs
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("running %v: %v", args, err)
+		}
+		code = exitErr.ExitCode()
+	}
+	return out.String(), errs.String(), code
+}
+
+func TestStdin(t *testing.T) {
+	stdout, stderr, code := runMain(t, "print 1 + 2;\nprint \"piped\";", "run", "-")
+	if code != 0 {
+		t.Fatalf("run - exited %d, reporting:\n%s", code, stderr)
+	}
+	if want := "3\npiped\n"; stdout != want {
+		t.Errorf("run - printed %q, want %q", stdout, want)
+	}
+
+	stdout, _, _ = runMain(t, "1;", "tokenize", "-")
+	if want := "NUMBER 1 1.0\nSEMICOLON ; null\nEOF  null\n"; stdout != want {
+		t.Errorf("tokenize - printed %q, want %q", stdout, want)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	for _, flag := range []string{"-h", "--help", "help"} {
+		stdout, stderr, code := runMain(t, "", flag)
+		if code != 0 {
+			t.Errorf("%s exited %d, reporting:\n%s", flag, code, stderr)
+		}
+		for _, command := range []string{"tokenize", "parse", "evaluate", "run"} {
+			if !strings.Contains(stdout, "  "+command+" ") {
+				t.Errorf("%s output doesn't list %s:\n%s", flag, command, stdout)
+			}
+		}
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	_, stderr, code := runMain(t, "", "frobnicate", "file.lox")
+	if code != 1 {
+		t.Errorf("unknown command exited %d, want 1", code)
+	}
+	if !strings.HasPrefix(stderr, "Logs from your program will appear here!\nUnknown command: frobnicate\n") ||
+		!strings.Contains(stderr, "  tokenize ") {
+		t.Errorf("unknown command reported:\n%s", stderr)
+	}
+}
+
+func TestRunFileRuntimeError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "concat.lox")
+	if err := os.WriteFile(path, []byte(`print "a" + 1;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, code := runMain(t, "", "run", path)
+	if code != 70 {
+		t.Errorf("run exited %d, want 70", code)
+	}
+	if !strings.Contains(stderr, "Operands must be two numbers or two strings.\n[line 1] in script\n") {
+		t.Errorf("run reported:\n%s", stderr)
+	}
+
+	stdout, _, code := runMain(t, "", "run", "--loose-concat", path)
+	if code != 0 || stdout != "a1\n" {
+		t.Errorf("run --loose-concat exited %d, printing %q", code, stdout)
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	_, stderr, code := runMain(t, strings.Repeat("@", 50), "run", "--max-errors", "3", "-")
+	if code != 65 || strings.Count(stderr, "Unexpected character") != 3 || !strings.Contains(stderr, "Too many errors.") {
+		t.Errorf("run --max-errors 3 exited %d, reporting:\n%s", code, stderr)
+	}
+}
+
+func TestExit(t *testing.T) {
+	stdout, _, code := runMain(t, "print 1;\nprint 2;\nexit(3);\nprint 4;", "run", "-")
+	if code != 3 || stdout != "1\n2\n" {
+		t.Errorf("run exited %d, printing %q; want 3, printing \"1\\n2\\n\"", code, stdout)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loop.lox")
+	if err := os.WriteFile(path, []byte("while (true) {}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, code := runMain(t, "", "run", "--timeout", "100ms", path)
+	if code != 70 || !strings.HasSuffix(stderr, "Execution timed out.\n") {
+		t.Errorf("run --timeout exited %d, reporting:\n%s", code, stderr)
+	}
+}
+
+func TestRunIsDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.lox")
+	if err := os.WriteFile(path, []byte(`print "hello";`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, code := runMain(t, "", path)
+	if code != 0 || stdout != "hello\n" {
+		t.Errorf("running %s exited %d, printing %q, reporting:\n%s", path, code, stdout, stderr)
+	}
+}
+
+func TestRunPrompt(t *testing.T) {
+	var out, errs bytes.Buffer
+	in := strings.NewReader("var a = ;\nprint 1 + 2;\nvar b = 4;\nb * 2\n")
+	if code := RunPrompt(in, &out, &errs); code != 0 {
+		t.Errorf("RunPrompt returned %d, want 0", code)
+	}
+	if want := "> > 3\n> > 8\n> \nExit\n"; out.String() != want {
+		t.Errorf("RunPrompt printed %q, want %q", out.String(), want)
+	}
+	if want := "[line 1] Error at ';': Expect expression.\nvar a = ;\n        ^\n"; errs.String() != want {
+		t.Errorf("RunPrompt reported:\n%s\nwant:\n%s", errs.String(), want)
+	}
+
+	out.Reset()
+	errs.Reset()
+	in = strings.NewReader("print \"bye\";\nexit(3);\nprint \"not run\";\n")
+	if code := RunPrompt(in, &out, &errs); code != 3 {
+		t.Errorf("RunPrompt ending in exit(3) returned %d", code)
+	}
+	if want := "> bye\n> "; out.String() != want {
+		t.Errorf("RunPrompt ending in exit(3) printed %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,68 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// LoxArray is the runtime representation of an array, a growable list of
+// values. Arrays are shared by reference like instances.
+type LoxArray struct {
+	elements []any
+}
+
+// index checks that value is an in-bounds index, reporting errors at bracket.
+// Negative indices are an error rather than counting from the end.
+func (a *LoxArray) index(bracket Token, value any) int {
+	number, ok := value.(LoxNumber)
+	if !ok || number.value != math.Trunc(number.value) {
+		panic(RuntimeError{Token: bracket, Message: "Array index must be an integer."})
+	}
+	if number.value < 0 || number.value >= float64(len(a.elements)) {
+		panic(RuntimeError{Token: bracket, Message: fmt.Sprintf(
+			"Array index %v is out of bounds for length %d.", number.value, len(a.elements))})
+	}
+	return int(number.value)
+}
+
+func (a *LoxArray) get(bracket Token, index any) any {
+	return a.elements[a.index(bracket, index)]
+}
+
+func (a *LoxArray) set(bracket Token, index any, value any) {
+	a.elements[a.index(bracket, index)] = value
+}
+
+func (a *LoxArray) String() string {
+	return a.stringify(map[any]bool{})
+}
+
+// stringify prints the array as an element of the containers in printing,
+// which it must not print again
+func (a *LoxArray) stringify(printing map[any]bool) string {
+	if printing[a] {
+		return "[...]"
+	}
+	printing[a] = true
+	defer delete(printing, a)
+
+	elements := make([]string, len(a.elements))
+	for i, element := range a.elements {
+		elements[i] = stringifyElement(element, printing)
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// stringifyElement is stringify for a value inside the containers in
+// printing, so a container that holds itself prints as [...] or {...}
+// rather than recursing forever
+func stringifyElement(value any, printing map[any]bool) string {
+	switch value := value.(type) {
+	case *LoxArray:
+		return value.stringify(printing)
+	case *LoxMap:
+		return value.stringify(printing)
+	}
+	return stringify(value)
+}
@@ -0,0 +1,37 @@
+package lox
+
+import "testing"
+
+func TestArrays(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"literal", `print [1, "a", nil, true, 2.5];`, "[1, a, nil, true, 2.5]"},
+		{"empty", `print [];`, "[]"},
+		{"index", `var a = [1, 2, 3]; print a[0] + a[2];`, "4"},
+		{"assign", `var a = [1, 2, 3]; a[1] = 9; print a;`, "[1, 9, 3]"},
+		{"assignment value", `var a = [1]; print a[0] = 5;`, "5"},
+		{"nested", `var a = [[1], [2, [3]]]; print a[1][1][0]; print a;`, "3\n[[1], [2, [3]]]"},
+		{"shared by reference", `fun set(a) { a[0] = "set"; } var a = [1]; set(a); print a;`, "[set]"},
+		{"shared element", `var b = [1, 2]; print [b, b];`, "[[1, 2], [1, 2]]"},
+		{"contains itself", `var a = [1]; a[0] = a; print a;`, "[[...]]"},
+		{"cycle", `var a = [1]; var b = [a]; a[0] = b; print a; print b;`, "[[[...]]]\n[[[...]]]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestArrayIndexErrors(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{`var a = [1, 2]; print a[2];`, "Array index 2 is out of bounds for length 2."},
+		{`var a = [1, 2]; print a[-1];`, "Array index -1 is out of bounds for length 2."},
+		{`var a = [1, 2]; a[5] = 1;`, "Array index 5 is out of bounds for length 2."},
+		{`var a = [1, 2]; print a[0.5];`, "Array index must be an integer."},
+		{`var a = [1, 2]; print a["0"];`, "Array index must be an integer."},
+		{`print 1[0];`, "Only arrays and maps can be indexed."},
+	}
+	for _, test := range tests {
+		expectError(t, test.source, ExitRuntimeError, test.want+"\n[line 1] in script\n")
+	}
+}
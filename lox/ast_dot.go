@@ -0,0 +1,361 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DotAST renders a parsed program as a Graphviz DOT graph with one node per
+// AST node and edges to its children in order. Node IDs are assigned in
+// traversal order, so the same program always gives the same graph. Each
+// function body is drawn in its own cluster.
+func DotAST(statements []Stmt) string {
+	p := &dotPrinter{indent: 1}
+	p.builder.WriteString("digraph AST {\n")
+	p.line("node [shape=box];")
+	root := p.node("program")
+	for _, stmt := range statements {
+		p.edge(root, p.stmt(stmt))
+	}
+	p.builder.WriteString("}\n")
+	return p.builder.String()
+}
+
+// dotPrinter writes nodes and edges as it visits the tree. Each Visit
+// method returns the ID of the node it wrote.
+type dotPrinter struct {
+	builder  strings.Builder
+	indent   int
+	nextID   int
+	clusters int
+}
+
+func (p *dotPrinter) line(format string, args ...any) {
+	p.builder.WriteString(strings.Repeat("  ", p.indent))
+	fmt.Fprintf(&p.builder, format, args...)
+	p.builder.WriteByte('\n')
+}
+
+func (p *dotPrinter) node(label string) string {
+	id := fmt.Sprintf("n%d", p.nextID)
+	p.nextID++
+	p.line("%s [label=\"%s\"];", id, dotEscape(label))
+	return id
+}
+
+// edge links parent to child, skipping missing optional children
+func (p *dotPrinter) edge(parent string, child string) {
+	if child != "" {
+		p.line("%s -> %s;", parent, child)
+	}
+}
+
+func (p *dotPrinter) expr(expr Expr) string {
+	if expr == nil {
+		return ""
+	}
+	return expr.Accept(p).(string)
+}
+
+func (p *dotPrinter) stmt(stmt Stmt) string {
+	if stmt == nil {
+		return ""
+	}
+	return stmt.Accept(p).(string)
+}
+
+// dotEscape escapes a label for use inside a double-quoted DOT string
+func dotEscape(label string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(label)
+}
+
+func (p *dotPrinter) VisitLiteralExpr(literal *Literal) any {
+	if text, ok := literal.Value.(LoxString); ok {
+		return p.node(`"` + text.value + `"`)
+	}
+	return p.node(literal.Value.RawPrint())
+}
+
+func (p *dotPrinter) VisitBinaryExpr(binary *Binary) any {
+	id := p.node(binary.Op.lexeme)
+	p.edge(id, p.expr(binary.Left))
+	p.edge(id, p.expr(binary.Right))
+	return id
+}
+
+func (p *dotPrinter) VisitInterpolationExpr(interpolation *Interpolation) any {
+	id := p.node("interpolation")
+	for _, part := range interpolation.Parts {
+		p.edge(id, p.expr(part))
+	}
+	return id
+}
+
+func (p *dotPrinter) VisitGroupingExpr(grouping *Grouping) any {
+	id := p.node("group")
+	p.edge(id, p.expr(grouping.Expression))
+	return id
+}
+
+func (p *dotPrinter) VisitUnaryExpr(unary *Unary) any {
+	id := p.node(unary.Op.lexeme)
+	p.edge(id, p.expr(unary.Right))
+	return id
+}
+
+func (p *dotPrinter) VisitLogicalExpr(logical *Logical) any {
+	id := p.node(logical.Op.lexeme)
+	p.edge(id, p.expr(logical.Left))
+	p.edge(id, p.expr(logical.Right))
+	return id
+}
+
+func (p *dotPrinter) VisitVariableExpr(variable *Variable) any {
+	return p.node(variable.Name.lexeme)
+}
+
+func (p *dotPrinter) VisitAssignExpr(assign *Assign) any {
+	id := p.node(assign.Name.lexeme + " =")
+	p.edge(id, p.expr(assign.Value))
+	return id
+}
+
+func (p *dotPrinter) VisitCallExpr(call *Call) any {
+	id := p.node("call")
+	p.edge(id, p.expr(call.Callee))
+	for _, argument := range call.Arguments {
+		p.edge(id, p.expr(argument))
+	}
+	return id
+}
+
+func (p *dotPrinter) VisitConditionalExpr(conditional *Conditional) any {
+	id := p.node("?:")
+	p.edge(id, p.expr(conditional.Condition))
+	p.edge(id, p.expr(conditional.ThenBranch))
+	p.edge(id, p.expr(conditional.ElseBranch))
+	return id
+}
+
+func (p *dotPrinter) VisitGetExpr(get *Get) any {
+	id := p.node("." + get.Name.lexeme)
+	p.edge(id, p.expr(get.Object))
+	return id
+}
+
+func (p *dotPrinter) VisitSetExpr(set *Set) any {
+	id := p.node("." + set.Name.lexeme + " =")
+	p.edge(id, p.expr(set.Object))
+	p.edge(id, p.expr(set.Value))
+	return id
+}
+
+func (p *dotPrinter) VisitThisExpr(this *This) any {
+	return p.node("this")
+}
+
+func (p *dotPrinter) VisitSuperExpr(super *Super) any {
+	return p.node("super." + super.Method.lexeme)
+}
+
+func (p *dotPrinter) VisitFunctionExpr(function *FunctionExpr) any {
+	return p.VisitFunctionStmt(function.Declaration)
+}
+
+func (p *dotPrinter) VisitArrayLiteralExpr(array *ArrayLiteral) any {
+	id := p.node("[]")
+	for _, element := range array.Elements {
+		p.edge(id, p.expr(element))
+	}
+	return id
+}
+
+func (p *dotPrinter) VisitMapLiteralExpr(literal *MapLiteral) any {
+	id := p.node("{}")
+	for i, key := range literal.Keys {
+		entry := p.node(":")
+		p.edge(entry, p.expr(key))
+		p.edge(entry, p.expr(literal.Values[i]))
+		p.edge(id, entry)
+	}
+	return id
+}
+
+func (p *dotPrinter) VisitIndexExpr(index *Index) any {
+	id := p.node("[index]")
+	p.edge(id, p.expr(index.Object))
+	p.edge(id, p.expr(index.Index))
+	return id
+}
+
+func (p *dotPrinter) VisitIndexAssignExpr(assign *IndexAssign) any {
+	id := p.node("[index] =")
+	p.edge(id, p.expr(assign.Object))
+	p.edge(id, p.expr(assign.Index))
+	p.edge(id, p.expr(assign.Value))
+	return id
+}
+
+func (p *dotPrinter) VisitExpressionStmt(stmt *Expression) any {
+	id := p.node("expression")
+	p.edge(id, p.expr(stmt.Expression))
+	return id
+}
+
+func (p *dotPrinter) VisitPrintStmt(stmt *Print) any {
+	id := p.node("print")
+	p.edge(id, p.expr(stmt.Expression))
+	return id
+}
+
+func (p *dotPrinter) VisitVarStmt(stmt *Var) any {
+	keyword := "var "
+	if stmt.Const {
+		keyword = "const "
+	}
+	id := p.node(keyword + stmt.Name.lexeme)
+	p.edge(id, p.expr(stmt.Initializer))
+	return id
+}
+
+func (p *dotPrinter) VisitBlockStmt(stmt *Block) any {
+	id := p.node("block")
+	for _, inner := range stmt.Statements {
+		p.edge(id, p.stmt(inner))
+	}
+	return id
+}
+
+func (p *dotPrinter) VisitIfStmt(stmt *If) any {
+	id := p.node("if")
+	p.edge(id, p.expr(stmt.Condition))
+	p.edge(id, p.stmt(stmt.ThenBranch))
+	p.edge(id, p.stmt(stmt.ElseBranch))
+	return id
+}
+
+func (p *dotPrinter) VisitWhileStmt(stmt *While) any {
+	if stmt.DoWhile {
+		id := p.node("do-while")
+		p.edge(id, p.stmt(stmt.Body))
+		p.edge(id, p.expr(stmt.Condition))
+		return id
+	}
+	id := p.node("while")
+	p.edge(id, p.expr(stmt.Condition))
+	p.edge(id, p.stmt(stmt.Body))
+	p.edge(id, p.expr(stmt.Increment))
+	return id
+}
+
+func (p *dotPrinter) VisitFunctionStmt(stmt *Function) any {
+	params := make([]string, len(stmt.Params))
+	for i, param := range stmt.Params {
+		params[i] = param.lexeme
+	}
+	name := stmt.Name.lexeme
+	if name == "" {
+		name = "<anonymous>"
+	}
+	label := fmt.Sprintf("fun %s(%s)", name, strings.Join(params, ", "))
+	if stmt.IsGetter {
+		label = "fun " + name
+	}
+	id := p.node(label)
+
+	p.line("subgraph cluster_%d {", p.clusters)
+	p.clusters++
+	p.indent++
+	p.line("label=\"%s\";", dotEscape(name))
+	for _, inner := range stmt.Body {
+		p.edge(id, p.stmt(inner))
+	}
+	p.indent--
+	p.line("}")
+	return id
+}
+
+func (p *dotPrinter) VisitReturnStmt(stmt *Return) any {
+	id := p.node("return")
+	p.edge(id, p.expr(stmt.Value))
+	return id
+}
+
+func (p *dotPrinter) VisitClassStmt(stmt *Class) any {
+	label := "class " + stmt.Name.lexeme
+	if stmt.Superclass != nil {
+		label += " < " + stmt.Superclass.Name.lexeme
+	}
+	id := p.node(label)
+	for _, method := range stmt.StaticMethods {
+		p.edge(id, p.VisitFunctionStmt(method).(string))
+	}
+	for _, method := range stmt.Methods {
+		p.edge(id, p.VisitFunctionStmt(method).(string))
+	}
+	return id
+}
+
+func (p *dotPrinter) VisitBreakStmt(stmt *Break) any {
+	return p.node("break")
+}
+
+func (p *dotPrinter) VisitContinueStmt(stmt *Continue) any {
+	return p.node("continue")
+}
+
+func (p *dotPrinter) VisitSwitchStmt(stmt *Switch) any {
+	id := p.node("switch")
+	p.edge(id, p.expr(stmt.Subject))
+	for _, arm := range stmt.Cases {
+		armID := p.node("case")
+		p.edge(armID, p.expr(arm.Value))
+		for _, inner := range arm.Body {
+			p.edge(armID, p.stmt(inner))
+		}
+		p.edge(id, armID)
+	}
+	if stmt.Default != nil {
+		armID := p.node("default")
+		for _, inner := range stmt.Default {
+			p.edge(armID, p.stmt(inner))
+		}
+		p.edge(id, armID)
+	}
+	return id
+}
+
+func (p *dotPrinter) VisitThrowStmt(stmt *Throw) any {
+	id := p.node("throw")
+	p.edge(id, p.expr(stmt.Value))
+	return id
+}
+
+func (p *dotPrinter) VisitTryStmt(stmt *Try) any {
+	id := p.node("try")
+	for _, inner := range stmt.Body {
+		p.edge(id, p.stmt(inner))
+	}
+	if stmt.Catch != nil {
+		clause := p.node("catch " + stmt.CatchName.lexeme)
+		for _, inner := range stmt.Catch {
+			p.edge(clause, p.stmt(inner))
+		}
+		p.edge(id, clause)
+	}
+	if stmt.Finally != nil {
+		clause := p.node("finally")
+		for _, inner := range stmt.Finally {
+			p.edge(clause, p.stmt(inner))
+		}
+		p.edge(id, clause)
+	}
+	return id
+}
+
+func (p *dotPrinter) VisitForEachStmt(stmt *ForEach) any {
+	id := p.node("for " + stmt.Name.lexeme + " in")
+	p.edge(id, p.expr(stmt.Collection))
+	p.edge(id, p.stmt(stmt.Body))
+	return id
+}
@@ -0,0 +1,54 @@
+package lox
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDotAST compares the graph of each testdata/dot/*.lox program with the
+// .dot file beside it, and has Graphviz check the graph when it's installed.
+// Run with -update to rewrite the .dot files.
+func TestDotAST(t *testing.T) {
+	programs, err := filepath.Glob(filepath.Join("testdata", "dot", "*.lox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, program := range programs {
+		name := strings.TrimSuffix(filepath.Base(program), ".lox")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			statements, errors := parse(t, string(source))
+			if len(errors) > 0 {
+				t.Fatalf("Parse errors: %v", errors)
+			}
+			got := DotAST(statements)
+
+			goldenPath := strings.TrimSuffix(program, ".lox") + ".dot"
+			if *update {
+				if err := os.WriteFile(goldenPath, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			} else if want, err := os.ReadFile(goldenPath); err != nil {
+				t.Fatal(err)
+			} else if got != string(want) {
+				t.Errorf("DotAST(%s) =\n%s\nwant:\n%s", program, got, want)
+			}
+
+			dot, err := exec.LookPath("dot")
+			if err != nil {
+				t.Skip("Graphviz dot is not installed")
+			}
+			cmd := exec.Command(dot, "-Tsvg")
+			cmd.Stdin = strings.NewReader(got)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("dot -Tsvg rejected the graph: %v\n%s", err, output)
+			}
+		})
+	}
+}
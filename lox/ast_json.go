@@ -0,0 +1,647 @@
+package lox
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalAST encodes a parsed program as JSON. Every node is an object
+// tagged with its type under "node", and tokens keep their lexeme and
+// source position, so UnmarshalAST can rebuild an equivalent tree.
+func MarshalAST(statements []Stmt) ([]byte, error) {
+	return json.MarshalIndent(astMarshaler{}.stmts(statements), "", "  ")
+}
+
+// UnmarshalAST rebuilds a program from the output of MarshalAST.
+func UnmarshalAST(data []byte) (statements []Stmt, err error) {
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			astErr, ok := r.(astError)
+			if !ok {
+				panic(r)
+			}
+			statements, err = nil, astErr
+		}
+	}()
+	return astUnmarshaler{}.stmts(tree), nil
+}
+
+type astError string
+
+func (err astError) Error() string {
+	return "invalid AST: " + string(err)
+}
+
+type jsonNode = map[string]any
+
+// astMarshaler turns each node into a jsonNode.
+type astMarshaler struct{}
+
+func (m astMarshaler) expr(expr Expr) any {
+	if expr == nil {
+		return nil
+	}
+	return expr.Accept(m)
+}
+
+func (m astMarshaler) exprs(exprs []Expr) []any {
+	ret := make([]any, len(exprs))
+	for i, expr := range exprs {
+		ret[i] = m.expr(expr)
+	}
+	return ret
+}
+
+func (m astMarshaler) stmt(stmt Stmt) any {
+	if stmt == nil {
+		return nil
+	}
+	return stmt.Accept(m)
+}
+
+func (m astMarshaler) stmts(stmts []Stmt) []any {
+	ret := make([]any, len(stmts))
+	for i, stmt := range stmts {
+		ret[i] = m.stmt(stmt)
+	}
+	return ret
+}
+
+func (m astMarshaler) token(tok Token) any {
+	if tok == (Token{}) {
+		// Anonymous functions have no name
+		return nil
+	}
+	return jsonNode{
+		"type":   tok._type.String(),
+		"lexeme": tok.lexeme,
+		"line":   tok.line,
+		"column": tok.column,
+		"start":  tok.startOffset,
+		"end":    tok.endOffset,
+	}
+}
+
+func (m astMarshaler) tokens(toks []Token) []any {
+	ret := make([]any, len(toks))
+	for i, tok := range toks {
+		ret[i] = m.token(tok)
+	}
+	return ret
+}
+
+func (m astMarshaler) functions(functions []*Function) []any {
+	ret := make([]any, len(functions))
+	for i, function := range functions {
+		ret[i] = m.VisitFunctionStmt(function)
+	}
+	return ret
+}
+
+// literal tags the value with its Lox type, a bare JSON number or string
+// would not say which LoxLiteral to rebuild.
+func (m astMarshaler) literal(value LoxLiteral) any {
+	switch v := value.(type) {
+	case LoxNumber:
+		return jsonNode{"type": "number", "value": v.value}
+	case LoxString:
+		return jsonNode{"type": "string", "value": v.value}
+	case LoxBoolean:
+		return jsonNode{"type": "boolean", "value": v.value}
+	default:
+		return jsonNode{"type": "nil"}
+	}
+}
+
+func (m astMarshaler) VisitLiteralExpr(literal *Literal) any {
+	return jsonNode{"node": "Literal", "value": m.literal(literal.Value), "token": m.token(literal.Token)}
+}
+
+func (m astMarshaler) VisitBinaryExpr(binary *Binary) any {
+	return jsonNode{
+		"node":  "Binary",
+		"op":    m.token(binary.Op),
+		"left":  m.expr(binary.Left),
+		"right": m.expr(binary.Right),
+	}
+}
+
+func (m astMarshaler) VisitInterpolationExpr(interpolation *Interpolation) any {
+	return jsonNode{"node": "Interpolation", "parts": m.exprs(interpolation.Parts)}
+}
+
+func (m astMarshaler) VisitGroupingExpr(grouping *Grouping) any {
+	return jsonNode{"node": "Grouping", "expression": m.expr(grouping.Expression)}
+}
+
+func (m astMarshaler) VisitUnaryExpr(unary *Unary) any {
+	return jsonNode{"node": "Unary", "op": m.token(unary.Op), "right": m.expr(unary.Right)}
+}
+
+func (m astMarshaler) VisitLogicalExpr(logical *Logical) any {
+	return jsonNode{
+		"node":  "Logical",
+		"op":    m.token(logical.Op),
+		"left":  m.expr(logical.Left),
+		"right": m.expr(logical.Right),
+	}
+}
+
+func (m astMarshaler) VisitVariableExpr(variable *Variable) any {
+	return jsonNode{"node": "Variable", "name": m.token(variable.Name)}
+}
+
+func (m astMarshaler) VisitAssignExpr(assign *Assign) any {
+	return jsonNode{"node": "Assign", "name": m.token(assign.Name), "value": m.expr(assign.Value)}
+}
+
+func (m astMarshaler) VisitCallExpr(call *Call) any {
+	return jsonNode{
+		"node":      "Call",
+		"callee":    m.expr(call.Callee),
+		"paren":     m.token(call.Paren),
+		"arguments": m.exprs(call.Arguments),
+	}
+}
+
+func (m astMarshaler) VisitConditionalExpr(conditional *Conditional) any {
+	return jsonNode{
+		"node":       "Conditional",
+		"condition":  m.expr(conditional.Condition),
+		"thenBranch": m.expr(conditional.ThenBranch),
+		"elseBranch": m.expr(conditional.ElseBranch),
+	}
+}
+
+func (m astMarshaler) VisitGetExpr(get *Get) any {
+	return jsonNode{"node": "Get", "object": m.expr(get.Object), "name": m.token(get.Name)}
+}
+
+func (m astMarshaler) VisitSetExpr(set *Set) any {
+	return jsonNode{
+		"node":   "Set",
+		"object": m.expr(set.Object),
+		"name":   m.token(set.Name),
+		"value":  m.expr(set.Value),
+	}
+}
+
+func (m astMarshaler) VisitThisExpr(this *This) any {
+	return jsonNode{"node": "This", "keyword": m.token(this.Keyword)}
+}
+
+func (m astMarshaler) VisitSuperExpr(super *Super) any {
+	return jsonNode{"node": "Super", "keyword": m.token(super.Keyword), "method": m.token(super.Method)}
+}
+
+func (m astMarshaler) VisitFunctionExpr(function *FunctionExpr) any {
+	return jsonNode{
+		"node":        "FunctionExpr",
+		"keyword":     m.token(function.Keyword),
+		"declaration": m.VisitFunctionStmt(function.Declaration),
+	}
+}
+
+func (m astMarshaler) VisitArrayLiteralExpr(array *ArrayLiteral) any {
+	return jsonNode{"node": "ArrayLiteral", "bracket": m.token(array.Bracket), "elements": m.exprs(array.Elements)}
+}
+
+func (m astMarshaler) VisitIndexExpr(index *Index) any {
+	return jsonNode{
+		"node":    "Index",
+		"object":  m.expr(index.Object),
+		"bracket": m.token(index.Bracket),
+		"index":   m.expr(index.Index),
+	}
+}
+
+func (m astMarshaler) VisitIndexAssignExpr(assign *IndexAssign) any {
+	return jsonNode{
+		"node":    "IndexAssign",
+		"object":  m.expr(assign.Object),
+		"bracket": m.token(assign.Bracket),
+		"index":   m.expr(assign.Index),
+		"value":   m.expr(assign.Value),
+	}
+}
+
+func (m astMarshaler) VisitMapLiteralExpr(literal *MapLiteral) any {
+	return jsonNode{
+		"node":   "MapLiteral",
+		"brace":  m.token(literal.Brace),
+		"keys":   m.exprs(literal.Keys),
+		"values": m.exprs(literal.Values),
+	}
+}
+
+func (m astMarshaler) VisitExpressionStmt(stmt *Expression) any {
+	return jsonNode{"node": "Expression", "expression": m.expr(stmt.Expression)}
+}
+
+func (m astMarshaler) VisitPrintStmt(stmt *Print) any {
+	return jsonNode{"node": "Print", "expression": m.expr(stmt.Expression)}
+}
+
+func (m astMarshaler) VisitVarStmt(stmt *Var) any {
+	return jsonNode{"node": "Var", "name": m.token(stmt.Name), "initializer": m.expr(stmt.Initializer), "const": stmt.Const}
+}
+
+func (m astMarshaler) VisitBlockStmt(stmt *Block) any {
+	return jsonNode{"node": "Block", "statements": m.stmts(stmt.Statements)}
+}
+
+func (m astMarshaler) VisitIfStmt(stmt *If) any {
+	return jsonNode{
+		"node":       "If",
+		"condition":  m.expr(stmt.Condition),
+		"thenBranch": m.stmt(stmt.ThenBranch),
+		"elseBranch": m.stmt(stmt.ElseBranch),
+	}
+}
+
+func (m astMarshaler) VisitWhileStmt(stmt *While) any {
+	return jsonNode{
+		"node":      "While",
+		"condition": m.expr(stmt.Condition),
+		"body":      m.stmt(stmt.Body),
+		"increment": m.expr(stmt.Increment),
+		"doWhile":   stmt.DoWhile,
+	}
+}
+
+func (m astMarshaler) VisitFunctionStmt(stmt *Function) any {
+	return jsonNode{
+		"node":     "Function",
+		"name":     m.token(stmt.Name),
+		"params":   m.tokens(stmt.Params),
+		"body":     m.stmts(stmt.Body),
+		"isGetter": stmt.IsGetter,
+	}
+}
+
+func (m astMarshaler) VisitReturnStmt(stmt *Return) any {
+	return jsonNode{"node": "Return", "keyword": m.token(stmt.Keyword), "value": m.expr(stmt.Value)}
+}
+
+func (m astMarshaler) VisitClassStmt(stmt *Class) any {
+	var superclass any
+	if stmt.Superclass != nil {
+		superclass = m.VisitVariableExpr(stmt.Superclass)
+	}
+	return jsonNode{
+		"node":          "Class",
+		"name":          m.token(stmt.Name),
+		"superclass":    superclass,
+		"methods":       m.functions(stmt.Methods),
+		"staticMethods": m.functions(stmt.StaticMethods),
+	}
+}
+
+func (m astMarshaler) VisitBreakStmt(stmt *Break) any {
+	return jsonNode{"node": "Break", "keyword": m.token(stmt.Keyword)}
+}
+
+func (m astMarshaler) VisitContinueStmt(stmt *Continue) any {
+	return jsonNode{"node": "Continue", "keyword": m.token(stmt.Keyword)}
+}
+
+func (m astMarshaler) VisitSwitchStmt(stmt *Switch) any {
+	cases := make([]any, len(stmt.Cases))
+	for i, arm := range stmt.Cases {
+		cases[i] = jsonNode{"value": m.expr(arm.Value), "body": m.stmts(arm.Body)}
+	}
+	var defaultBody any
+	if stmt.Default != nil {
+		defaultBody = m.stmts(stmt.Default)
+	}
+	return jsonNode{
+		"node":    "Switch",
+		"subject": m.expr(stmt.Subject),
+		"cases":   cases,
+		"default": defaultBody,
+	}
+}
+
+func (m astMarshaler) VisitForEachStmt(stmt *ForEach) any {
+	return jsonNode{
+		"node":       "ForEach",
+		"name":       m.token(stmt.Name),
+		"in":         m.token(stmt.In),
+		"collection": m.expr(stmt.Collection),
+		"body":       m.stmt(stmt.Body),
+	}
+}
+
+func (m astMarshaler) VisitThrowStmt(stmt *Throw) any {
+	return jsonNode{"node": "Throw", "keyword": m.token(stmt.Keyword), "value": m.expr(stmt.Value)}
+}
+
+func (m astMarshaler) VisitTryStmt(stmt *Try) any {
+	var catchBody, finallyBody any
+	if stmt.Catch != nil {
+		catchBody = m.stmts(stmt.Catch)
+	}
+	if stmt.Finally != nil {
+		finallyBody = m.stmts(stmt.Finally)
+	}
+	return jsonNode{
+		"node":      "Try",
+		"body":      m.stmts(stmt.Body),
+		"catchName": m.token(stmt.CatchName),
+		"catch":     catchBody,
+		"finally":   finallyBody,
+	}
+}
+
+// tokenTypes maps the names printed by TokenType.String back to the type.
+var tokenTypes = func() map[string]TokenType {
+	types := make(map[string]TokenType)
+	for _type := LEFT_PAREN; _type <= EOF; _type++ {
+		types[_type.String()] = _type
+	}
+	return types
+}()
+
+// astUnmarshaler rebuilds nodes from the generic values encoding/json
+// decodes into. Malformed input panics with an astError.
+type astUnmarshaler struct{}
+
+func (u astUnmarshaler) fail(format string, args ...any) {
+	panic(astError(fmt.Sprintf(format, args...)))
+}
+
+func (u astUnmarshaler) object(value any) jsonNode {
+	node, ok := value.(jsonNode)
+	if !ok {
+		u.fail("expected an object, got %v", value)
+	}
+	return node
+}
+
+func (u astUnmarshaler) list(value any) []any {
+	if value == nil {
+		return nil
+	}
+	list, ok := value.([]any)
+	if !ok {
+		u.fail("expected a list, got %v", value)
+	}
+	return list
+}
+
+func (u astUnmarshaler) string(node jsonNode, key string) string {
+	value, ok := node[key].(string)
+	if !ok {
+		u.fail("expected a string for %q", key)
+	}
+	return value
+}
+
+func (u astUnmarshaler) number(node jsonNode, key string) float64 {
+	value, ok := node[key].(float64)
+	if !ok {
+		u.fail("expected a number for %q", key)
+	}
+	return value
+}
+
+func (u astUnmarshaler) bool(node jsonNode, key string) bool {
+	value, ok := node[key].(bool)
+	if !ok {
+		u.fail("expected a boolean for %q", key)
+	}
+	return value
+}
+
+func (u astUnmarshaler) token(value any) Token {
+	if value == nil {
+		return Token{}
+	}
+	node := u.object(value)
+	name := u.string(node, "type")
+	_type, ok := tokenTypes[name]
+	if !ok {
+		u.fail("unknown token type %q", name)
+	}
+	return Token{
+		_type:   _type,
+		lexeme:  u.string(node, "lexeme"),
+		literal: LoxEmptyLiteral{},
+		line:    int(u.number(node, "line")),
+		column:  int(u.number(node, "column")),
+
+		startOffset: int(u.number(node, "start")),
+		endOffset:   int(u.number(node, "end")),
+	}
+}
+
+func (u astUnmarshaler) tokens(value any) []Token {
+	var toks []Token
+	for _, tok := range u.list(value) {
+		toks = append(toks, u.token(tok))
+	}
+	return toks
+}
+
+func (u astUnmarshaler) literal(value any) LoxLiteral {
+	node := u.object(value)
+	switch kind := u.string(node, "type"); kind {
+	case "number":
+		return LoxNumber{value: u.number(node, "value")}
+	case "string":
+		return LoxString{value: u.string(node, "value")}
+	case "boolean":
+		return LoxBoolean{value: u.bool(node, "value")}
+	case "nil":
+		return LoxNil{}
+	default:
+		u.fail("unknown literal type %q", kind)
+		return nil
+	}
+}
+
+func (u astUnmarshaler) exprs(value any) []Expr {
+	var exprs []Expr
+	for _, expr := range u.list(value) {
+		exprs = append(exprs, u.expr(expr))
+	}
+	return exprs
+}
+
+func (u astUnmarshaler) expr(value any) Expr {
+	if value == nil {
+		return nil
+	}
+	node := u.object(value)
+	switch kind := u.string(node, "node"); kind {
+	case "Literal":
+		literal := &Literal{Value: u.literal(node["value"]), Token: u.token(node["token"])}
+		if literal.Token != (Token{}) {
+			literal.Token.literal = literal.Value
+		}
+		return literal
+	case "Binary":
+		return &Binary{Left: u.expr(node["left"]), Right: u.expr(node["right"]), Op: u.token(node["op"])}
+	case "Interpolation":
+		return &Interpolation{Parts: u.exprs(node["parts"])}
+	case "Grouping":
+		return &Grouping{Expression: u.expr(node["expression"])}
+	case "Unary":
+		return &Unary{Op: u.token(node["op"]), Right: u.expr(node["right"])}
+	case "Logical":
+		return &Logical{Left: u.expr(node["left"]), Right: u.expr(node["right"]), Op: u.token(node["op"])}
+	case "Variable":
+		return u.variable(node)
+	case "Assign":
+		return &Assign{Name: u.token(node["name"]), Value: u.expr(node["value"])}
+	case "Call":
+		return &Call{Callee: u.expr(node["callee"]), Paren: u.token(node["paren"]), Arguments: u.exprs(node["arguments"])}
+	case "Conditional":
+		return &Conditional{
+			Condition:  u.expr(node["condition"]),
+			ThenBranch: u.expr(node["thenBranch"]),
+			ElseBranch: u.expr(node["elseBranch"]),
+		}
+	case "Get":
+		return &Get{Object: u.expr(node["object"]), Name: u.token(node["name"])}
+	case "Set":
+		return &Set{Object: u.expr(node["object"]), Name: u.token(node["name"]), Value: u.expr(node["value"])}
+	case "This":
+		return &This{Keyword: u.token(node["keyword"])}
+	case "Super":
+		return &Super{Keyword: u.token(node["keyword"]), Method: u.token(node["method"])}
+	case "FunctionExpr":
+		return &FunctionExpr{Keyword: u.token(node["keyword"]), Declaration: u.function(node["declaration"])}
+	case "ArrayLiteral":
+		return &ArrayLiteral{Bracket: u.token(node["bracket"]), Elements: u.exprs(node["elements"])}
+	case "MapLiteral":
+		return &MapLiteral{Brace: u.token(node["brace"]), Keys: u.exprs(node["keys"]), Values: u.exprs(node["values"])}
+	case "Index":
+		return &Index{Object: u.expr(node["object"]), Bracket: u.token(node["bracket"]), Index: u.expr(node["index"])}
+	case "IndexAssign":
+		return &IndexAssign{
+			Object:  u.expr(node["object"]),
+			Bracket: u.token(node["bracket"]),
+			Index:   u.expr(node["index"]),
+			Value:   u.expr(node["value"]),
+		}
+	default:
+		u.fail("unknown expression node %q", kind)
+		return nil
+	}
+}
+
+func (u astUnmarshaler) variable(node jsonNode) *Variable {
+	return &Variable{Name: u.token(node["name"])}
+}
+
+func (u astUnmarshaler) function(value any) *Function {
+	node := u.object(value)
+	return &Function{
+		Name:     u.token(node["name"]),
+		Params:   u.tokens(node["params"]),
+		Body:     u.stmts(node["body"]),
+		IsGetter: u.bool(node, "isGetter"),
+	}
+}
+
+func (u astUnmarshaler) functions(value any) []*Function {
+	var functions []*Function
+	for _, function := range u.list(value) {
+		functions = append(functions, u.function(function))
+	}
+	return functions
+}
+
+func (u astUnmarshaler) stmts(value any) []Stmt {
+	list := u.list(value)
+	stmts := make([]Stmt, 0, len(list))
+	for _, stmt := range list {
+		stmts = append(stmts, u.stmt(stmt))
+	}
+	return stmts
+}
+
+func (u astUnmarshaler) stmt(value any) Stmt {
+	if value == nil {
+		return nil
+	}
+	node := u.object(value)
+	switch kind := u.string(node, "node"); kind {
+	case "Expression":
+		return &Expression{Expression: u.expr(node["expression"])}
+	case "Print":
+		return &Print{Expression: u.expr(node["expression"])}
+	case "Var":
+		return &Var{Name: u.token(node["name"]), Initializer: u.expr(node["initializer"]), Const: u.bool(node, "const")}
+	case "Block":
+		return &Block{Statements: u.stmts(node["statements"])}
+	case "If":
+		return &If{
+			Condition:  u.expr(node["condition"]),
+			ThenBranch: u.stmt(node["thenBranch"]),
+			ElseBranch: u.stmt(node["elseBranch"]),
+		}
+	case "While":
+		return &While{
+			Condition: u.expr(node["condition"]),
+			Body:      u.stmt(node["body"]),
+			Increment: u.expr(node["increment"]),
+			DoWhile:   u.bool(node, "doWhile"),
+		}
+	case "Function":
+		return u.function(node)
+	case "Return":
+		return &Return{Keyword: u.token(node["keyword"]), Value: u.expr(node["value"])}
+	case "Class":
+		class := &Class{
+			Name:          u.token(node["name"]),
+			Methods:       u.functions(node["methods"]),
+			StaticMethods: u.functions(node["staticMethods"]),
+		}
+		if node["superclass"] != nil {
+			class.Superclass = u.variable(u.object(node["superclass"]))
+		}
+		return class
+	case "Break":
+		return &Break{Keyword: u.token(node["keyword"])}
+	case "Continue":
+		return &Continue{Keyword: u.token(node["keyword"])}
+	case "Switch":
+		stmt := &Switch{Subject: u.expr(node["subject"])}
+		for _, arm := range u.list(node["cases"]) {
+			arm := u.object(arm)
+			stmt.Cases = append(stmt.Cases, SwitchCase{Value: u.expr(arm["value"]), Body: u.stmts(arm["body"])})
+		}
+		if node["default"] != nil {
+			stmt.Default = u.stmts(node["default"])
+		}
+		return stmt
+	case "ForEach":
+		return &ForEach{
+			Name:       u.token(node["name"]),
+			In:         u.token(node["in"]),
+			Collection: u.expr(node["collection"]),
+			Body:       u.stmt(node["body"]),
+		}
+	case "Throw":
+		return &Throw{Keyword: u.token(node["keyword"]), Value: u.expr(node["value"])}
+	case "Try":
+		stmt := &Try{Body: u.stmts(node["body"]), CatchName: u.token(node["catchName"])}
+		if node["catch"] != nil {
+			stmt.Catch = u.stmts(node["catch"])
+		}
+		if node["finally"] != nil {
+			stmt.Finally = u.stmts(node["finally"])
+		}
+		return stmt
+	default:
+		u.fail("unknown statement node %q", kind)
+		return nil
+	}
+}
@@ -0,0 +1,111 @@
+package lox
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestASTRoundTrip(t *testing.T) {
+	statements, errors := parse(t, `
+		var a = 1.5e3 + 0x1F * -(2 ** 3);
+		const greeting = "hi ${a} there";
+		fun add(x, y) { return x + y; }
+		class Point < Base {
+			init(x) { this.x = x; super.init(); }
+			class origin() { return Point(0); }
+			length { return this.x; }
+		}
+		var f = fun (n) { return n == nil or !n and true; };
+		var list = [1, "two", nil, false];
+		var map = {"a": 1, "b": list[0]};
+		list[1] = map["a"];
+		a += 1;
+		print a > 1 ? add(a, 2) : f(a), "done";
+		if (a) { print 1; } else print 2;
+		while (a < 10) { a = a + 1; if (a == 5) break; else continue; }
+		for (var i = 0; i < 3; i = i + 1) print i;
+		for (var x in list) print x;
+		do { a = a - 1; } while (a > 0);
+		switch (a) { case 1: print "one"; default: print "other"; }
+		try { throw "oops"; } catch (e) { print e; }`)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+
+	data, err := MarshalAST(statements)
+	if err != nil {
+		t.Fatalf("MarshalAST: %v", err)
+	}
+	decoded, err := UnmarshalAST(data)
+	if err != nil {
+		t.Fatalf("UnmarshalAST: %v\n%s", err, data)
+	}
+	if got, want := FormatAST(decoded), FormatAST(statements); got != want {
+		t.Errorf("round trip formats as:\n%s\nwant:\n%s", got, want)
+	}
+	// Positions and literal values aren't part of the formatted text
+	again, err := MarshalAST(decoded)
+	if err != nil {
+		t.Fatalf("MarshalAST(decoded): %v", err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Errorf("round trip marshals as:\n%s\nwant:\n%s", again, data)
+	}
+}
+
+func TestUnmarshalASTErrors(t *testing.T) {
+	for _, data := range []string{
+		`{}`,
+		`[{"node": "Bogus"}]`,
+		`[{"node": "Print", "expression": {"node": "Literal", "value": []}}]`,
+	} {
+		if statements, err := UnmarshalAST([]byte(data)); err == nil {
+			t.Errorf("UnmarshalAST(%s) = %v, want an error", data, statements)
+		}
+	}
+}
+
+// TestMarshalAST compares the JSON of each testdata/json/*.lox program with
+// the .json file beside it. Run with -update to rewrite the .json files.
+func TestMarshalAST(t *testing.T) {
+	programs, err := filepath.Glob(filepath.Join("testdata", "json", "*.lox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, program := range programs {
+		name := strings.TrimSuffix(filepath.Base(program), ".lox")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			statements, errors := parse(t, string(source))
+			if len(errors) > 0 {
+				t.Fatalf("Parse errors: %v", errors)
+			}
+			got, err := MarshalAST(statements)
+			if err != nil {
+				t.Fatalf("MarshalAST: %v", err)
+			}
+			got = append(got, '\n')
+
+			goldenPath := strings.TrimSuffix(program, ".lox") + ".json"
+			if *update {
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("MarshalAST(%s) =\n%s\nwant:\n%s", program, got, want)
+			}
+		})
+	}
+}
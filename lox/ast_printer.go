@@ -0,0 +1,238 @@
+package lox
+
+import "strings"
+
+// AstPrinter renders syntax trees as Lisp-like S-expressions, e.g.
+// (* (- 123.0) (group 45.67)), so precedence is explicit.
+type AstPrinter struct{}
+
+// Print renders an expression
+func (p AstPrinter) Print(expr Expr) string {
+	return expr.Accept(p).(string)
+}
+
+// PrintStmt renders a statement
+func (p AstPrinter) PrintStmt(stmt Stmt) string {
+	return stmt.Accept(p).(string)
+}
+
+// parenthesize wraps name and its parts in parentheses. Parts may be
+// strings, expressions or statements, nil parts are skipped.
+func (p AstPrinter) parenthesize(name string, parts ...any) string {
+	var builder strings.Builder
+	builder.WriteString("(" + name)
+	for _, part := range parts {
+		switch part := part.(type) {
+		case string:
+			builder.WriteString(" " + part)
+		case Expr:
+			if part != nil {
+				builder.WriteString(" " + p.Print(part))
+			}
+		case Stmt:
+			if part != nil {
+				builder.WriteString(" " + p.PrintStmt(part))
+			}
+		}
+	}
+	builder.WriteString(")")
+	return builder.String()
+}
+
+func (p AstPrinter) exprs(exprs []Expr) []any {
+	parts := make([]any, len(exprs))
+	for i, expr := range exprs {
+		parts[i] = expr
+	}
+	return parts
+}
+
+func (p AstPrinter) stmts(stmts []Stmt) []any {
+	parts := make([]any, len(stmts))
+	for i, stmt := range stmts {
+		parts[i] = stmt
+	}
+	return parts
+}
+
+func (p AstPrinter) function(function *Function) string {
+	params := make([]string, len(function.Params))
+	for i, param := range function.Params {
+		params[i] = param.lexeme
+	}
+	parts := []any{"(" + strings.Join(params, " ") + ")"}
+	if function.Name.lexeme != "" {
+		parts = append([]any{function.Name.lexeme}, parts...)
+	}
+	return p.parenthesize("fun", append(parts, p.stmts(function.Body)...)...)
+}
+
+func (p AstPrinter) VisitLiteralExpr(literal *Literal) any {
+	return literal.Value.RawPrint()
+}
+
+func (p AstPrinter) VisitBinaryExpr(binary *Binary) any {
+	return p.parenthesize(binary.Op.lexeme, binary.Left, binary.Right)
+}
+
+func (p AstPrinter) VisitInterpolationExpr(interpolation *Interpolation) any {
+	return p.parenthesize("interpolate", p.exprs(interpolation.Parts)...)
+}
+
+func (p AstPrinter) VisitGroupingExpr(grouping *Grouping) any {
+	return p.parenthesize("group", grouping.Expression)
+}
+
+func (p AstPrinter) VisitUnaryExpr(unary *Unary) any {
+	return p.parenthesize(unary.Op.lexeme, unary.Right)
+}
+
+func (p AstPrinter) VisitLogicalExpr(logical *Logical) any {
+	return p.parenthesize(logical.Op.lexeme, logical.Left, logical.Right)
+}
+
+func (p AstPrinter) VisitVariableExpr(variable *Variable) any {
+	return variable.Name.lexeme
+}
+
+func (p AstPrinter) VisitAssignExpr(assign *Assign) any {
+	return p.parenthesize("=", assign.Name.lexeme, assign.Value)
+}
+
+func (p AstPrinter) VisitCallExpr(call *Call) any {
+	return p.parenthesize("call", append([]any{call.Callee}, p.exprs(call.Arguments)...)...)
+}
+
+func (p AstPrinter) VisitConditionalExpr(conditional *Conditional) any {
+	return p.parenthesize("?:", conditional.Condition, conditional.ThenBranch, conditional.ElseBranch)
+}
+
+func (p AstPrinter) VisitGetExpr(get *Get) any {
+	return p.parenthesize(".", get.Object, get.Name.lexeme)
+}
+
+func (p AstPrinter) VisitSetExpr(set *Set) any {
+	return p.parenthesize("=", p.parenthesize(".", set.Object, set.Name.lexeme), set.Value)
+}
+
+func (p AstPrinter) VisitThisExpr(this *This) any {
+	return "this"
+}
+
+func (p AstPrinter) VisitSuperExpr(super *Super) any {
+	return p.parenthesize("super", super.Method.lexeme)
+}
+
+func (p AstPrinter) VisitFunctionExpr(function *FunctionExpr) any {
+	return p.function(function.Declaration)
+}
+
+func (p AstPrinter) VisitArrayLiteralExpr(array *ArrayLiteral) any {
+	return p.parenthesize("array", p.exprs(array.Elements)...)
+}
+
+func (p AstPrinter) VisitIndexExpr(index *Index) any {
+	return p.parenthesize("[]", index.Object, index.Index)
+}
+
+func (p AstPrinter) VisitIndexAssignExpr(assign *IndexAssign) any {
+	return p.parenthesize("=", p.parenthesize("[]", assign.Object, assign.Index), assign.Value)
+}
+
+func (p AstPrinter) VisitMapLiteralExpr(literal *MapLiteral) any {
+	entries := make([]any, len(literal.Keys))
+	for i, key := range literal.Keys {
+		entries[i] = p.parenthesize(":", key, literal.Values[i])
+	}
+	return p.parenthesize("map", entries...)
+}
+
+func (p AstPrinter) VisitExpressionStmt(stmt *Expression) any {
+	return p.parenthesize(";", stmt.Expression)
+}
+
+func (p AstPrinter) VisitPrintStmt(stmt *Print) any {
+	return p.parenthesize("print", stmt.Expression)
+}
+
+func (p AstPrinter) VisitVarStmt(stmt *Var) any {
+	if stmt.Const {
+		return p.parenthesize("const", stmt.Name.lexeme, stmt.Initializer)
+	}
+	return p.parenthesize("var", stmt.Name.lexeme, stmt.Initializer)
+}
+
+func (p AstPrinter) VisitBlockStmt(stmt *Block) any {
+	return p.parenthesize("block", p.stmts(stmt.Statements)...)
+}
+
+func (p AstPrinter) VisitIfStmt(stmt *If) any {
+	return p.parenthesize("if", stmt.Condition, stmt.ThenBranch, stmt.ElseBranch)
+}
+
+func (p AstPrinter) VisitWhileStmt(stmt *While) any {
+	if stmt.DoWhile {
+		return p.parenthesize("do-while", stmt.Body, stmt.Condition)
+	}
+	return p.parenthesize("while", stmt.Condition, stmt.Body, stmt.Increment)
+}
+
+func (p AstPrinter) VisitFunctionStmt(stmt *Function) any {
+	return p.function(stmt)
+}
+
+func (p AstPrinter) VisitReturnStmt(stmt *Return) any {
+	return p.parenthesize("return", stmt.Value)
+}
+
+func (p AstPrinter) VisitClassStmt(stmt *Class) any {
+	parts := []any{stmt.Name.lexeme}
+	if stmt.Superclass != nil {
+		parts = append(parts, "<", stmt.Superclass.Name.lexeme)
+	}
+	for _, method := range stmt.StaticMethods {
+		parts = append(parts, p.parenthesize("class", p.function(method)))
+	}
+	for _, method := range stmt.Methods {
+		parts = append(parts, p.function(method))
+	}
+	return p.parenthesize("class", parts...)
+}
+
+func (p AstPrinter) VisitBreakStmt(stmt *Break) any {
+	return "(break)"
+}
+
+func (p AstPrinter) VisitContinueStmt(stmt *Continue) any {
+	return "(continue)"
+}
+
+func (p AstPrinter) VisitSwitchStmt(stmt *Switch) any {
+	parts := []any{stmt.Subject}
+	for _, arm := range stmt.Cases {
+		parts = append(parts, p.parenthesize("case", append([]any{arm.Value}, p.stmts(arm.Body)...)...))
+	}
+	if stmt.Default != nil {
+		parts = append(parts, p.parenthesize("default", p.stmts(stmt.Default)...))
+	}
+	return p.parenthesize("switch", parts...)
+}
+
+func (p AstPrinter) VisitThrowStmt(stmt *Throw) any {
+	return p.parenthesize("throw", stmt.Value)
+}
+
+func (p AstPrinter) VisitTryStmt(stmt *Try) any {
+	parts := []any{p.parenthesize("block", p.stmts(stmt.Body)...)}
+	if stmt.Catch != nil {
+		parts = append(parts, p.parenthesize("catch", append([]any{stmt.CatchName.lexeme}, p.stmts(stmt.Catch)...)...))
+	}
+	if stmt.Finally != nil {
+		parts = append(parts, p.parenthesize("finally", p.stmts(stmt.Finally)...))
+	}
+	return p.parenthesize("try", parts...)
+}
+
+func (p AstPrinter) VisitForEachStmt(stmt *ForEach) any {
+	return p.parenthesize("for-in", stmt.Name.lexeme, stmt.Collection, stmt.Body)
+}
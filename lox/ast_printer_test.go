@@ -0,0 +1,63 @@
+package lox
+
+import "testing"
+
+// expectPrinted fails unless source parses as a single expression that
+// AstPrinter prints as want
+func expectPrinted(t *testing.T, source string, want string) {
+	t.Helper()
+	tokens, scanErrors := Tokenize(source)
+	if len(scanErrors) > 0 {
+		t.Fatalf("Tokenize(%q) errors: %v", source, scanErrors)
+	}
+	parser := NewParser(tokens)
+	expr, errors := parser.ParseExpression()
+	if len(errors) > 0 {
+		t.Fatalf("ParseExpression(%q) errors: %v", source, errors)
+	}
+	if got := (AstPrinter{}).Print(expr); got != want {
+		t.Errorf("Print(%q) = %s, want %s", source, got, want)
+	}
+}
+
+func TestPrintPostfixChain(t *testing.T) {
+	expectPrinted(t, "a.b(1)[2].c().d", "(. (call (. ([] (call (. a b) 1.0) 2.0) c)) d)")
+	expectPrinted(t, "f(1)(2)", "(call (call f 1.0) 2.0)")
+	expectPrinted(t, "a[0][1] = x.y", "(= ([] ([] a 0.0) 1.0) (. x y))")
+	// Calling a number parses, it only fails when run
+	expectPrinted(t, "1()", "(call 1.0)")
+}
+
+func TestPostfixChainErrors(t *testing.T) {
+	for source, want := range map[string]string{
+		"a.(b)": "[line 1] Error at '(': Expect property name after '.'.",
+		"a[1":   "[line 1] Error at end: Expect ']' after index.",
+		"f(1":   "[line 1] Error at end: Expect ')' after arguments.",
+	} {
+		tokens, _ := Tokenize(source)
+		parser := NewParser(tokens)
+		if _, errors := parser.ParseExpression(); len(errors) != 1 || errors[0].Error() != want {
+			t.Errorf("ParseExpression(%q) errors = %v, want %q", source, errors, want)
+		}
+	}
+	expectError(t, "1();", ExitRuntimeError, "Can only call functions and classes.\n[line 1] in script\n")
+}
+
+func TestPrintLogical(t *testing.T) {
+	// and binds tighter than or
+	expectPrinted(t, "a or b and c", "(or a (and b c))")
+	expectPrinted(t, "a and b or c", "(or (and a b) c)")
+	expectPrinted(t, "(a or b) and c", "(and (group (or a b)) c)")
+	// Distinct from the arithmetic and comparison operators around them
+	expectPrinted(t, "a + 1 or b == 2", "(or (+ a 1.0) (== b 2.0))")
+	expectPrinted(t, "!a or -b", "(or (! a) (- b))")
+
+	tokens, _ := Tokenize("a or b and c")
+	parser := NewParser(tokens)
+	expr, _ := parser.ParseExpression()
+	if or, ok := expr.(*Logical); !ok {
+		t.Errorf("a or b and c parsed as %T, want *Logical", expr)
+	} else if _, ok := or.Right.(*Logical); !ok {
+		t.Errorf("b and c parsed as %T, want *Logical", or.Right)
+	}
+}
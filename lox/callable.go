@@ -0,0 +1,87 @@
+package lox
+
+import "fmt"
+
+// LoxCallable is implemented by every value that can be called
+type LoxCallable interface {
+	Arity() int
+	Call(evaluator *Evaluator, arguments []any) any
+}
+
+// returnValue carries a return statement's value up to the enclosing call
+type returnValue struct {
+	value any
+}
+
+// loopBreak unwinds to the innermost enclosing loop or switch
+type loopBreak struct{}
+
+// loopContinue unwinds to the end of the innermost enclosing loop's body
+type loopContinue struct{}
+
+// exitRequest unwinds the whole program when exit() is called. Interpret and
+// Evaluate return it as their error so the runner can use its code.
+type exitRequest struct {
+	code int
+}
+
+func (exit exitRequest) Error() string {
+	return fmt.Sprintf("exit(%d)", exit.code)
+}
+
+// LoxFunction is the runtime representation of a function declaration
+type LoxFunction struct {
+	declaration   *Function
+	closure       *Environment // The environment the function was declared in
+	isInitializer bool         // Initializers always return "this"
+	isGetter      bool         // Getters are called when accessed
+}
+
+// bind returns a copy of the method whose closure defines "this"
+func (f *LoxFunction) bind(instance *LoxInstance) *LoxFunction {
+	environment := NewEnvironment(f.closure)
+	environment.define("this", instance)
+	return &LoxFunction{
+		declaration:   f.declaration,
+		closure:       environment,
+		isInitializer: f.isInitializer,
+		isGetter:      f.isGetter,
+	}
+}
+
+func (f *LoxFunction) Arity() int {
+	return len(f.declaration.Params)
+}
+
+func (f *LoxFunction) Call(evaluator *Evaluator, arguments []any) (result any) {
+	environment := NewEnvironment(f.closure)
+	for i, param := range f.declaration.Params {
+		environment.define(param.lexeme, arguments[i])
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			ret, ok := r.(returnValue)
+			if !ok {
+				panic(r)
+			}
+			result = ret.value
+			if f.isInitializer {
+				result = f.closure.getAt(0, "this")
+			}
+		}
+	}()
+
+	evaluator.executeBlock(f.declaration.Body, environment)
+	if f.isInitializer {
+		return f.closure.getAt(0, "this")
+	}
+	return LoxNil{}
+}
+
+func (f *LoxFunction) String() string {
+	if f.declaration.Name.lexeme == "" {
+		return "<fn>"
+	}
+	return "<fn " + f.declaration.Name.lexeme + ">"
+}
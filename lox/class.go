@@ -0,0 +1,85 @@
+package lox
+
+import "fmt"
+
+// LoxClass is the runtime representation of a class declaration. Calling it
+// constructs a new instance.
+type LoxClass struct {
+	name          string
+	superclass    *LoxClass // nil when the class doesn't inherit
+	methods       map[string]*LoxFunction
+	staticMethods map[string]*LoxFunction
+}
+
+// findMethod looks up a method on the class, then up the superclass chain
+func (c *LoxClass) findMethod(name string) *LoxFunction {
+	if method, ok := c.methods[name]; ok {
+		return method
+	}
+	if c.superclass != nil {
+		return c.superclass.findMethod(name)
+	}
+	return nil
+}
+
+// get looks up a static method, which may be inherited. Getters are called.
+func (c *LoxClass) get(evaluator *Evaluator, name Token) any {
+	for class := c; class != nil; class = class.superclass {
+		if method, ok := class.staticMethods[name.lexeme]; ok {
+			if method.isGetter {
+				return evaluator.call(method, name, nil)
+			}
+			return method
+		}
+	}
+	panic(RuntimeError{Token: name, Message: fmt.Sprintf("Undefined property '%s'.", name.lexeme)})
+}
+
+// Arity is that of the class's initializer, if it has one
+func (c *LoxClass) Arity() int {
+	if initializer := c.findMethod("init"); initializer != nil {
+		return initializer.Arity()
+	}
+	return 0
+}
+
+func (c *LoxClass) Call(evaluator *Evaluator, arguments []any) any {
+	instance := &LoxInstance{class: c, fields: make(map[string]any)}
+	if initializer := c.findMethod("init"); initializer != nil {
+		initializer.bind(instance).Call(evaluator, arguments)
+	}
+	return instance
+}
+
+func (c *LoxClass) String() string {
+	return c.name
+}
+
+// LoxInstance is an instance of a LoxClass with its own fields
+type LoxInstance struct {
+	class  *LoxClass
+	fields map[string]any
+}
+
+// get looks up a property, fields shadow methods of the same name. Getters
+// are called rather than returned.
+func (i *LoxInstance) get(evaluator *Evaluator, name Token) any {
+	if value, ok := i.fields[name.lexeme]; ok {
+		return value
+	}
+	if method := i.class.findMethod(name.lexeme); method != nil {
+		if method.isGetter {
+			return evaluator.call(method.bind(i), name, nil)
+		}
+		return method.bind(i)
+	}
+	panic(RuntimeError{Token: name, Message: fmt.Sprintf("Undefined property '%s'.", name.lexeme)})
+}
+
+func (i *LoxInstance) set(name Token, value any) {
+	i.fields[name.lexeme] = value
+}
+
+func (i *LoxInstance) String() string {
+	return i.class.name + " instance"
+}
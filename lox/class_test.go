@@ -0,0 +1,159 @@
+package lox
+
+import "testing"
+
+func TestClasses(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"print class and instance", `class Bagel {} print Bagel; print Bagel();`, "Bagel\nBagel instance"},
+		{"fields", `class Box {} var b = Box(); b.size = 3; b.size = b.size + 1; print b.size;`, "4"},
+		{"methods", `class Cat { speak() { return "meow"; } } print Cat().speak();`, "meow"},
+		{"field shadows method", `
+			class Cat { speak() { return "meow"; } }
+			var c = Cat();
+			c.speak = fun () { return "purr"; };
+			print c.speak();
+			print Cat().speak();`, "purr\nmeow"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestUndefinedProperty(t *testing.T) {
+	expectError(t, "class A {}\nprint A().missing;", ExitRuntimeError, "Undefined property 'missing'.\n[line 2] in script\n")
+}
+
+func TestThis(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"method reads fields", `
+			class Person {
+				greet() { return "Hi, " + this.name; }
+			}
+			var p = Person();
+			p.name = "Ann";
+			print p.greet();`, "Hi, Ann"},
+		{"method moved to another instance", `
+			class Person {
+				sayName() { print this.name; }
+			}
+			var jane = Person();
+			jane.name = "Jane";
+			var bill = Person();
+			bill.name = "Bill";
+			bill.sayName = jane.sayName;
+			bill.sayName();`, "Jane"},
+		{"callback remembers its instance", `
+			class Thing {
+				getCallback() {
+					fun localFunction() { print this; }
+					return localFunction;
+				}
+			}
+			var callback = Thing().getCallback();
+			callback();`, "Thing instance"},
+		{"bound method in a variable", `
+			class Counter {
+				inc() { this.n = this.n + 1; return this.n; }
+			}
+			var c = Counter();
+			c.n = 0;
+			var inc = c.inc;
+			inc();
+			print inc();`, "2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestThisOutsideMethod(t *testing.T) {
+	expectError(t, "fun notAMethod() { print this; }", ExitSyntaxError,
+		"[line 1] Error at 'this': Can't use 'this' outside of a class.\n")
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"fields set by init", `
+			class Point {
+				init(x, y) { this.x = x; this.y = y; }
+			}
+			var p = Point(1, 2);
+			print p.x + p.y;`, "3"},
+		{"calling init again", `
+			class Point {
+				init(x, y) { this.x = x; this.y = y; }
+			}
+			var p = Point(1, 2);
+			var q = p.init(5, 6);
+			print p.x;
+			print q == p;`, "5\ntrue"},
+		{"bare return gives this", `
+			class A {
+				init() {
+					this.v = 1;
+					if (true) return;
+					this.v = 2;
+				}
+			}
+			print A().init().v;`, "1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestInitArity(t *testing.T) {
+	expectError(t, "class Point { init(x, y) {} }\nPoint();", ExitRuntimeError,
+		"Expected 2 arguments but got 0.\n[line 2] in script\n")
+}
+
+func TestInheritanceErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		code   int
+		want   string
+	}{
+		{"class A < A {}", ExitSyntaxError, "[line 1] Error at 'A': A class can't inherit from itself.\n"},
+		{"print super.x;", ExitSyntaxError, "[line 1] Error at 'super': Can't use 'super' outside of a class.\n"},
+		{"class A { f() { super.f(); } }", ExitSyntaxError,
+			"[line 1] Error at 'super': Can't use 'super' in a class with no superclass.\n"},
+		{"var N = 1;\nclass A < N {}", ExitRuntimeError, "Superclass must be a class.\n[line 2] in script\n"},
+	}
+	for _, test := range tests {
+		expectError(t, test.source, test.code, test.want)
+	}
+}
+
+func TestStaticMethods(t *testing.T) {
+	expectOutput(t, `
+		class Math {
+			class square(n) { return n * n; }
+			square() { return "instance"; }
+		}
+		print Math.square(3);
+		print Math().square();`, "9\ninstance\n")
+
+	expectError(t, "class A { method() {} }\nA.method();", ExitRuntimeError, "Undefined property 'method'.\n[line 2] in script\n")
+	expectError(t, "class A { class make() { return this; } }", ExitSyntaxError,
+		"[line 1] Error at 'this': Can't use 'this' in a static method.\n")
+}
+
+func TestGetters(t *testing.T) {
+	expectOutput(t, `
+		class Circle {
+			init(radius) { this.radius = radius; }
+			area { return 3 * this.radius * this.radius; }
+		}
+		class Ring < Circle {}
+		print Circle(2).area;
+		print Ring(1).area;`, "12\n3\n")
+
+	expectError(t, "class Square { area { return 4; } }\nSquare().area();", ExitRuntimeError,
+		"Can only call functions and classes.\n[line 2] in script\n")
+}
@@ -0,0 +1,82 @@
+package lox
+
+import "fmt"
+
+// Environment maps variable names to values for one scope, falling back to
+// the enclosing scope on lookup misses
+type Environment struct {
+	values    map[string]any
+	constants map[string]bool // Names defined with const, created on demand
+	enclosing *Environment
+}
+
+func NewEnvironment(enclosing *Environment) *Environment {
+	return &Environment{
+		values:    make(map[string]any),
+		enclosing: enclosing,
+	}
+}
+
+// define binds name in this scope. Redefining an existing name is allowed,
+// so the REPL can re-declare globals; locals are checked by the Resolver.
+func (env *Environment) define(name string, value any) {
+	env.values[name] = value
+	delete(env.constants, name)
+}
+
+// defineConst binds name in this scope like define, but later assignments
+// to it fail
+func (env *Environment) defineConst(name string, value any) {
+	env.values[name] = value
+	if env.constants == nil {
+		env.constants = make(map[string]bool)
+	}
+	env.constants[name] = true
+}
+
+// get looks name up in this scope and then each enclosing one, returning a
+// RuntimeError at name if no scope defines it
+func (env *Environment) get(name Token) (any, error) {
+	for environment := env; environment != nil; environment = environment.enclosing {
+		if value, ok := environment.values[name.lexeme]; ok {
+			return value, nil
+		}
+	}
+	return nil, undefinedVariable(name)
+}
+
+// assign updates the nearest scope that defines name. Unlike define it
+// never creates a binding, so assigning an undeclared name is an error.
+func (env *Environment) assign(name Token, value any) error {
+	for environment := env; environment != nil; environment = environment.enclosing {
+		if _, ok := environment.values[name.lexeme]; ok {
+			if environment.constants[name.lexeme] {
+				return RuntimeError{Token: name, Message: fmt.Sprintf("Cannot assign to constant '%s'.", name.lexeme)}
+			}
+			environment.values[name.lexeme] = value
+			return nil
+		}
+	}
+	return undefinedVariable(name)
+}
+
+func undefinedVariable(name Token) RuntimeError {
+	return RuntimeError{Token: name, Message: fmt.Sprintf("Undefined variable '%s'.", name.lexeme)}
+}
+
+// ancestor walks a fixed number of hops up the enclosing chain
+func (env *Environment) ancestor(distance int) *Environment {
+	environment := env
+	for i := 0; i < distance; i++ {
+		environment = environment.enclosing
+	}
+	return environment
+}
+
+func (env *Environment) getAt(distance int, name string) any {
+	return env.ancestor(distance).values[name]
+}
+
+func (env *Environment) assignAt(distance int, name Token, value any) {
+	env.ancestor(distance).values[name.lexeme] = value
+}
@@ -0,0 +1,122 @@
+package lox
+
+import "testing"
+
+func identifier(name string) Token {
+	return Token{_type: IDENTIFIER, lexeme: name, line: 1}
+}
+
+// expectValue fails unless name is bound to want in env
+func expectValue(t *testing.T, env *Environment, name string, want any) {
+	t.Helper()
+	got, err := env.get(identifier(name))
+	if err != nil {
+		t.Fatalf("get(%s) error: %v", name, err)
+	}
+	if got != want {
+		t.Errorf("get(%s) = %v, want %v", name, got, want)
+	}
+}
+
+func TestEnvironmentShadowing(t *testing.T) {
+	globals := NewEnvironment(nil)
+	globals.define("a", LoxNumber{value: 1})
+	inner := NewEnvironment(globals)
+	inner.define("a", LoxNumber{value: 2})
+
+	expectValue(t, inner, "a", LoxNumber{value: 2})
+	expectValue(t, globals, "a", LoxNumber{value: 1})
+}
+
+func TestEnvironmentAssignThroughNesting(t *testing.T) {
+	globals := NewEnvironment(nil)
+	globals.define("a", LoxNumber{value: 1})
+	innermost := NewEnvironment(NewEnvironment(globals))
+
+	if err := innermost.assign(identifier("a"), LoxString{value: "set"}); err != nil {
+		t.Fatalf("assign error: %v", err)
+	}
+	expectValue(t, globals, "a", LoxString{value: "set"})
+	expectValue(t, innermost, "a", LoxString{value: "set"})
+}
+
+func TestEnvironmentRedefine(t *testing.T) {
+	globals := NewEnvironment(nil)
+	globals.define("a", LoxNumber{value: 1})
+	globals.define("a", LoxNil{})
+	expectValue(t, globals, "a", LoxNil{})
+}
+
+func TestEnvironmentUndefined(t *testing.T) {
+	globals := NewEnvironment(nil)
+	inner := NewEnvironment(globals)
+	want := "Undefined variable 'missing'.\n[line 1] in script"
+
+	if _, err := inner.get(identifier("missing")); err == nil || err.Error() != want {
+		t.Errorf("get error = %v, want %q", err, want)
+	}
+	if err := inner.assign(identifier("missing"), LoxNil{}); err == nil || err.Error() != want {
+		t.Errorf("assign error = %v, want %q", err, want)
+	}
+	// Assigning never defines
+	if _, err := globals.get(identifier("missing")); err == nil {
+		t.Error("assign defined the name")
+	}
+}
+
+// BenchmarkCapturedLookup compares reading a variable captured four scopes
+// up, as in the loop of BenchmarkCapturedLoop, by the distance the Resolver
+// found against walking the chain checking each scope like get.
+func BenchmarkCapturedLookup(b *testing.B) {
+	closure := NewEnvironment(NewEnvironment(nil))
+	closure.define("captured", LoxNumber{value: 1})
+	env := closure
+	for _, name := range []string{"i", "sum", "x"} {
+		env = NewEnvironment(env)
+		env.define(name, LoxNumber{value: 0})
+	}
+	name := identifier("captured")
+	distance := 3
+
+	b.Run("distance", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			env.getAt(distance, name.lexeme)
+		}
+	})
+	b.Run("chain", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := env.get(name); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkCapturedLoop runs a loop reading a captured variable a million
+// times
+func BenchmarkCapturedLoop(b *testing.B) {
+	tokens, _ := Tokenize(`
+		fun counter() {
+			var captured = 1;
+			fun count() {
+				var sum = 0;
+				for (var i = 0; i < 1000000; i = i + 1) {
+					var x = captured;
+					sum = sum + x;
+				}
+				return sum;
+			}
+			return count;
+		}
+		var count = counter();
+		count();`)
+	parser := NewParser(tokens)
+	statements, _ := parser.Parse()
+	for i := 0; i < b.N; i++ {
+		evaluator := NewEvaluator()
+		NewResolver(evaluator).Resolve(statements)
+		if err := evaluator.Interpret(statements); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
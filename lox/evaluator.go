@@ -0,0 +1,1012 @@
+package lox
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RuntimeError is an error raised while evaluating, at Token
+type RuntimeError struct {
+	Token   Token
+	Message string
+	trace   string // Stack trace when raised inside a call
+	thrown  any    // Value of a throw statement, nil for other errors
+}
+
+// caught is the value a catch clause binds for err: the thrown value, or
+// the message of an error raised by the interpreter
+func (err RuntimeError) caught() any {
+	if err.thrown != nil {
+		return err.thrown
+	}
+	return LoxString{value: err.Message}
+}
+
+func (err RuntimeError) Error() string {
+	if err.trace != "" {
+		return err.Message + "\n" + err.trace
+	}
+	return fmt.Sprintf("%s\n[line %d] in script", err.Message, err.Token.line)
+}
+
+// callFrame is a call in progress, kept for stack traces
+type callFrame struct {
+	callee LoxCallable
+	line   int // Line of the call site in the caller
+}
+
+// frameName describes a callee in a stack trace
+func frameName(callee LoxCallable) string {
+	switch callee := callee.(type) {
+	case *LoxFunction:
+		if name := callee.declaration.Name.lexeme; name != "" {
+			return "fn " + name + "()"
+		}
+		return "fn <anonymous>()"
+	case *NativeFunction:
+		return "native " + callee.name + "()"
+	case *LoxClass:
+		return "class " + callee.name + "()"
+	}
+	return fmt.Sprint(callee)
+}
+
+// Evaluator implements the Visitor and StmtVisitor interfaces
+type Evaluator struct {
+	globals     *Environment
+	environment *Environment
+	// Scope distance for each resolved local variable reference
+	locals map[Expr]int
+	// Calls in progress, innermost last. A runtime error leaves them in
+	// place until it is caught, so the trace can be built.
+	frames []callFrame
+	// Context of the program being interpreted, nil when there is none
+	ctx context.Context
+
+	// Out receives everything the program prints, os.Stdout by default
+	Out io.Writer
+	// Err receives the errors and warnings reported while running a
+	// program with this Evaluator, os.Stderr by default
+	Err io.Writer
+
+	// In is read by readLine() and readNumber(), os.Stdin by default
+	In    io.Reader
+	input *bufio.Reader // Buffers In, created on first read
+
+	exited bool // Set once the program calls exit()
+
+	// Source for random() and randomInt(), seeded from the clock unless the
+	// program calls seedRandom()
+	random *rand.Rand
+
+	// LooseConcat makes + stringify the other operand when either one is a
+	// string, so "count: " + 3 works
+	LooseConcat bool
+
+	// Strict makes reading a variable declared without an initializer an
+	// error until it has been assigned, instead of giving nil
+	Strict bool
+
+	// MaxCallDepth limits how many calls may be in progress at once, so runaway
+	// recursion is a runtime error rather than a crash. 0 means no limit.
+	MaxCallDepth int
+}
+
+// DefaultMaxCallDepth is the call depth limit of a new Evaluator
+const DefaultMaxCallDepth = 1000
+
+// uninitialized is the value of a variable declared without an initializer
+// in strict mode. It is never visible to Lox code.
+type uninitialized struct{}
+
+func NewEvaluator() *Evaluator {
+	globals := NewEnvironment(nil)
+	globals.define("len", &NativeFunction{name: "len", arity: 1, function: nativeLen})
+	globals.define("substring", &NativeFunction{name: "substring", arity: 3, function: nativeSubstring})
+	globals.define("indexOf", &NativeFunction{name: "indexOf", arity: 2, function: nativeIndexOf})
+	globals.define("charAt", &NativeFunction{name: "charAt", arity: 2, function: nativeCharAt})
+	globals.define("toUpper", &NativeFunction{name: "toUpper", arity: 1, function: stringNative("toUpper", strings.ToUpper)})
+	globals.define("toLower", &NativeFunction{name: "toLower", arity: 1, function: stringNative("toLower", strings.ToLower)})
+	globals.define("trim", &NativeFunction{name: "trim", arity: 1, function: stringNative("trim", strings.TrimSpace)})
+	globals.define("type", &NativeFunction{name: "type", arity: 1, function: nativeType})
+	globals.define("write", &NativeFunction{name: "write", arity: 1, function: nativeWrite})
+	globals.define("push", &NativeFunction{name: "push", arity: 2, function: nativePush})
+	globals.define("pop", &NativeFunction{name: "pop", arity: 1, function: nativePop})
+	globals.define("has", &NativeFunction{name: "has", arity: 2, function: nativeHas})
+	globals.define("keys", &NativeFunction{name: "keys", arity: 1, function: nativeKeys})
+	globals.define("floor", &NativeFunction{name: "floor", arity: 1, function: numberNative("floor", math.Floor)})
+	globals.define("ceil", &NativeFunction{name: "ceil", arity: 1, function: numberNative("ceil", math.Ceil)})
+	globals.define("round", &NativeFunction{name: "round", arity: 1, function: numberNative("round", math.Round)})
+	globals.define("mod", &NativeFunction{name: "mod", arity: 2, function: numbersNative("mod", mathMod)})
+	globals.define("sqrt", &NativeFunction{name: "sqrt", arity: 1, function: nativeSqrt})
+	globals.define("pow", &NativeFunction{name: "pow", arity: 2, function: numbersNative("pow", mathPow)})
+	globals.define("abs", &NativeFunction{name: "abs", arity: 1, function: numberNative("abs", math.Abs)})
+	globals.define("min", &NativeFunction{name: "min", arity: 2, function: numbersNative("min", mathMin)})
+	globals.define("max", &NativeFunction{name: "max", arity: 2, function: numbersNative("max", mathMax)})
+	globals.define("string", &NativeFunction{name: "string", arity: 1, function: nativeString})
+	globals.define("str", &NativeFunction{name: "str", arity: 1, function: nativeString})
+	globals.define("number", &NativeFunction{name: "number", arity: 1, function: numberConversion("number")})
+	globals.define("num", &NativeFunction{name: "num", arity: 1, function: numberConversion("num")})
+	globals.define("readLine", &NativeFunction{name: "readLine", arity: 0, function: nativeReadLine})
+	globals.define("readNumber", &NativeFunction{name: "readNumber", arity: 0, function: nativeReadNumber})
+	globals.define("exit", &NativeFunction{name: "exit", arity: 1, function: nativeExit})
+	globals.define("clock", &NativeFunction{name: "clock", arity: 0, function: nativeClock})
+	globals.define("assert", &NativeFunction{name: "assert", arity: 1, maxArity: 2, function: nativeAssert})
+	globals.define("random", &NativeFunction{name: "random", arity: 0, function: nativeRandom})
+	globals.define("randomInt", &NativeFunction{name: "randomInt", arity: 2, function: nativeRandomInt})
+	globals.define("seedRandom", &NativeFunction{name: "seedRandom", arity: 1, function: nativeSeedRandom})
+	globals.defineConst("PI", LoxNumber{value: math.Pi})
+
+	return &Evaluator{
+		globals:      globals,
+		environment:  globals,
+		locals:       make(map[Expr]int),
+		Out:          os.Stdout,
+		Err:          os.Stderr,
+		In:           os.Stdin,
+		random:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		MaxCallDepth: DefaultMaxCallDepth,
+	}
+}
+
+// Interpret executes the statements, stopping at the first runtime error
+func (e *Evaluator) Interpret(statements []Stmt) error {
+	return e.InterpretContext(context.Background(), statements)
+}
+
+// ErrInterrupted is returned, wrapping the context's error, when a program
+// is stopped because its context was cancelled or timed out
+var ErrInterrupted = errors.New("execution interrupted")
+
+// interrupted unwinds the program once its context is done
+type interrupted struct {
+	cause error
+}
+
+// InterpretContext is Interpret, stopping with ErrInterrupted when ctx is
+// done. The context is checked before each loop iteration and each call, so
+// even a program stuck in an infinite loop stops promptly.
+func (e *Evaluator) InterpretContext(ctx context.Context, statements []Stmt) (err error) {
+	e.ctx = ctx
+	defer func() { e.ctx = nil }()
+	defer e.catchRuntimeError(&err)
+	for _, stmt := range statements {
+		e.execute(stmt)
+	}
+	return nil
+}
+
+// checkInterrupt stops the program if its context is done
+func (e *Evaluator) checkInterrupt() {
+	if e.ctx == nil {
+		return
+	}
+	select {
+	case <-e.ctx.Done():
+		panic(interrupted{cause: e.ctx.Err()})
+	default:
+	}
+}
+
+// Evaluate evaluates a resolved expression, stopping at a runtime error
+func (e *Evaluator) Evaluate(expr Expr) (value any, err error) {
+	defer e.catchRuntimeError(&err)
+	return e.evaluate(expr), nil
+}
+
+// catchRuntimeError recovers a RuntimeError into err, adding a stack trace
+// if it was raised inside a call. It must be deferred directly, and
+// anything else keeps panicking.
+func (e *Evaluator) catchRuntimeError(err *error) {
+	if r := recover(); r != nil {
+		defer func() { e.frames = nil }()
+		switch r := r.(type) {
+		case RuntimeError:
+			if len(e.frames) > 0 {
+				r.trace = e.trace(r.Token.line)
+			}
+			*err = r
+		case exitRequest:
+			*err = r
+		case interrupted:
+			*err = fmt.Errorf("%w: %w", ErrInterrupted, r.cause)
+		default:
+			panic(r)
+		}
+	}
+}
+
+// traceEnds is how many frames a long stack trace keeps at each end
+const traceEnds = 10
+
+// trace lists the line each call in progress had reached, innermost first,
+// ending with the top level script. The middle of a deep stack is elided.
+func (e *Evaluator) trace(line int) string {
+	var lines []string
+	for i := len(e.frames) - 1; i >= 0; i-- {
+		depth := len(e.frames) - 1 - i
+		if depth < traceEnds || i < traceEnds {
+			lines = append(lines, fmt.Sprintf("[line %d] in %s", line, frameName(e.frames[i].callee)))
+		} else if depth == traceEnds {
+			lines = append(lines, fmt.Sprintf("... %d more calls", len(e.frames)-2*traceEnds))
+		}
+		line = e.frames[i].line
+	}
+	lines = append(lines, fmt.Sprintf("[line %d] in script", line))
+	return strings.Join(lines, "\n")
+}
+
+// call invokes callee on behalf of a call written at token, recording the
+// call for stack traces
+func (e *Evaluator) call(callee LoxCallable, token Token, arguments []any) any {
+	e.checkInterrupt()
+	if e.MaxCallDepth > 0 && len(e.frames) >= e.MaxCallDepth {
+		panic(RuntimeError{Token: token, Message: fmt.Sprintf("Stack overflow (max call depth %d exceeded).", e.MaxCallDepth)})
+	}
+	e.frames = append(e.frames, callFrame{callee: callee, line: token.line})
+	var result any
+	if native, ok := callee.(*NativeFunction); ok {
+		result = native.callAt(e, token, arguments)
+	} else {
+		result = callee.Call(e, arguments)
+	}
+	e.frames = e.frames[:len(e.frames)-1]
+	return result
+}
+
+// resolve is called by the Resolver for each local variable reference
+func (e *Evaluator) resolve(expr Expr, depth int) {
+	e.locals[expr] = depth
+}
+
+func (e *Evaluator) evaluate(expr Expr) any {
+	return expr.Accept(e)
+}
+
+func (e *Evaluator) execute(stmt Stmt) {
+	stmt.Accept(e)
+}
+
+// executeBlock runs statements in the given environment, restoring the
+// current one afterwards even if a return unwinds through it
+func (e *Evaluator) executeBlock(statements []Stmt, environment *Environment) {
+	previous := e.environment
+	defer func() {
+		e.environment = previous
+	}()
+
+	e.environment = environment
+	for _, stmt := range statements {
+		e.execute(stmt)
+	}
+}
+
+func (e *Evaluator) VisitExpressionStmt(stmt *Expression) any {
+	e.evaluate(stmt.Expression)
+	return nil
+}
+
+func (e *Evaluator) VisitPrintStmt(stmt *Print) any {
+	value := e.evaluate(stmt.Expression)
+	fmt.Fprintln(e.Out, stringify(value))
+	return nil
+}
+
+func (e *Evaluator) VisitVarStmt(stmt *Var) any {
+	var value any = LoxNil{}
+	if e.Strict {
+		value = uninitialized{}
+	}
+	if stmt.Initializer != nil {
+		value = e.evaluate(stmt.Initializer)
+	}
+	if stmt.Const {
+		e.environment.defineConst(stmt.Name.lexeme, value)
+	} else {
+		e.environment.define(stmt.Name.lexeme, value)
+	}
+	return nil
+}
+
+func (e *Evaluator) VisitBlockStmt(stmt *Block) any {
+	e.executeBlock(stmt.Statements, NewEnvironment(e.environment))
+	return nil
+}
+
+func (e *Evaluator) VisitIfStmt(stmt *If) any {
+	if isTruthy(e.evaluate(stmt.Condition)) {
+		e.execute(stmt.ThenBranch)
+	} else if stmt.ElseBranch != nil {
+		e.execute(stmt.ElseBranch)
+	}
+	return nil
+}
+
+func (e *Evaluator) VisitWhileStmt(stmt *While) any {
+	first := stmt.DoWhile
+	for first || isTruthy(e.evaluate(stmt.Condition)) {
+		first = false
+		e.checkInterrupt()
+		if broke := e.executeLoopBody(stmt.Body); broke {
+			break
+		}
+		if stmt.Increment != nil {
+			e.evaluate(stmt.Increment)
+		}
+	}
+	return nil
+}
+
+// VisitForEachStmt runs the body once per element in a fresh scope, so
+// closures capture that iteration's element. An array is iterated as it was
+// when the loop started, changing it in the body doesn't affect the loop.
+func (e *Evaluator) VisitForEachStmt(stmt *ForEach) any {
+	var elements []any
+	switch collection := e.evaluate(stmt.Collection).(type) {
+	case *LoxArray:
+		elements = append(elements, collection.elements...)
+	case LoxString:
+		for _, char := range collection.value {
+			elements = append(elements, LoxString{value: string(char)})
+		}
+	default:
+		panic(RuntimeError{Token: stmt.In, Message: "Can only iterate over arrays and strings."})
+	}
+
+	for _, element := range elements {
+		e.checkInterrupt()
+		environment := NewEnvironment(e.environment)
+		environment.define(stmt.Name.lexeme, element)
+		if broke := e.executeLoopBodyIn(stmt.Body, environment); broke {
+			break
+		}
+	}
+	return nil
+}
+
+// executeLoopBodyIn is executeLoopBody with environment as the current scope
+func (e *Evaluator) executeLoopBodyIn(body Stmt, environment *Environment) bool {
+	previous := e.environment
+	defer func() {
+		e.environment = previous
+	}()
+	e.environment = environment
+	return e.executeLoopBody(body)
+}
+
+// executeLoopBody runs one iteration of a loop, reporting whether it ended
+// with a break
+func (e *Evaluator) executeLoopBody(body Stmt) (broke bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r.(type) {
+			case loopBreak:
+				broke = true
+			case loopContinue:
+			default:
+				panic(r)
+			}
+		}
+	}()
+
+	e.execute(body)
+	return false
+}
+
+func (e *Evaluator) VisitFunctionStmt(stmt *Function) any {
+	function := &LoxFunction{declaration: stmt, closure: e.environment}
+	e.environment.define(stmt.Name.lexeme, function)
+	return nil
+}
+
+func (e *Evaluator) VisitClassStmt(stmt *Class) any {
+	var superclass *LoxClass
+	if stmt.Superclass != nil {
+		var ok bool
+		superclass, ok = e.evaluate(stmt.Superclass).(*LoxClass)
+		if !ok {
+			panic(RuntimeError{Token: stmt.Superclass.Name, Message: "Superclass must be a class."})
+		}
+	}
+
+	e.environment.define(stmt.Name.lexeme, nil)
+
+	// Static methods have no "this" or "super" to close over
+	staticMethods := make(map[string]*LoxFunction)
+	for _, method := range stmt.StaticMethods {
+		staticMethods[method.Name.lexeme] = &LoxFunction{
+			declaration: method,
+			closure:     e.environment,
+			isGetter:    method.IsGetter,
+		}
+	}
+
+	// Methods of a subclass close over a scope that defines "super"
+	if superclass != nil {
+		e.environment = NewEnvironment(e.environment)
+		e.environment.define("super", superclass)
+	}
+
+	methods := make(map[string]*LoxFunction)
+	for _, method := range stmt.Methods {
+		methods[method.Name.lexeme] = &LoxFunction{
+			declaration:   method,
+			closure:       e.environment,
+			isInitializer: method.Name.lexeme == "init",
+			isGetter:      method.IsGetter,
+		}
+	}
+
+	class := &LoxClass{
+		name:          stmt.Name.lexeme,
+		superclass:    superclass,
+		methods:       methods,
+		staticMethods: staticMethods,
+	}
+
+	if superclass != nil {
+		e.environment = e.environment.enclosing
+	}
+
+	if err := e.environment.assign(stmt.Name, class); err != nil {
+		panic(err)
+	}
+	return nil
+}
+
+func (e *Evaluator) VisitBreakStmt(stmt *Break) any {
+	panic(loopBreak{})
+}
+
+func (e *Evaluator) VisitContinueStmt(stmt *Continue) any {
+	panic(loopContinue{})
+}
+
+// VisitSwitchStmt runs the first arm whose value equals the subject, or
+// else the default arm. A break in the arm ends just the switch, as in C,
+// while continue still goes to the enclosing loop.
+func (e *Evaluator) VisitSwitchStmt(stmt *Switch) any {
+	subject := e.evaluate(stmt.Subject)
+	for _, arm := range stmt.Cases {
+		if isEqual(e.evaluate(arm.Value), subject) {
+			e.executeSwitchArm(arm.Body)
+			return nil
+		}
+	}
+	if stmt.Default != nil {
+		e.executeSwitchArm(stmt.Default)
+	}
+	return nil
+}
+
+// executeSwitchArm runs an arm of a switch in its own scope, stopping at a
+// break
+func (e *Evaluator) executeSwitchArm(body []Stmt) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(loopBreak); !ok {
+				panic(r)
+			}
+		}
+	}()
+	e.executeBlock(body, NewEnvironment(e.environment))
+}
+
+// VisitThrowStmt raises the value as a RuntimeError, so an uncaught throw
+// is reported like any other runtime error
+func (e *Evaluator) VisitThrowStmt(stmt *Throw) any {
+	value := e.evaluate(stmt.Value)
+	panic(RuntimeError{Token: stmt.Keyword, Message: stringify(value), thrown: value})
+}
+
+// VisitTryStmt runs the body, then the catch clause if the body raised a
+// RuntimeError, whether thrown or from the interpreter itself. The finally
+// clause runs however the statement is left, including by return, break,
+// continue or an error that isn't caught.
+func (e *Evaluator) VisitTryStmt(stmt *Try) any {
+	if stmt.Finally != nil {
+		defer e.executeBlock(stmt.Finally, NewEnvironment(e.environment))
+	}
+	if stmt.Catch == nil {
+		e.executeBlock(stmt.Body, NewEnvironment(e.environment))
+		return nil
+	}
+
+	depth := len(e.frames)
+	if err, ok := e.tryBlock(stmt.Body); !ok {
+		// Drop the frames of the calls the error unwound
+		e.frames = e.frames[:depth]
+		environment := NewEnvironment(e.environment)
+		environment.define(stmt.CatchName.lexeme, err.caught())
+		e.executeBlock(stmt.Catch, environment)
+	}
+	return nil
+}
+
+// tryBlock runs a try body, recovering a RuntimeError raised in it
+func (e *Evaluator) tryBlock(body []Stmt) (err RuntimeError, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			runtimeErr, isRuntimeErr := r.(RuntimeError)
+			if !isRuntimeErr {
+				panic(r)
+			}
+			err, ok = runtimeErr, false
+		}
+	}()
+	e.executeBlock(body, NewEnvironment(e.environment))
+	return RuntimeError{}, true
+}
+
+func (e *Evaluator) VisitReturnStmt(stmt *Return) any {
+	var value any = LoxNil{}
+	if stmt.Value != nil {
+		value = e.evaluate(stmt.Value)
+	}
+	panic(returnValue{value: value})
+}
+
+func (e *Evaluator) VisitLiteralExpr(literal *Literal) any {
+	return literal.Value
+}
+
+func (e *Evaluator) VisitBinaryExpr(binary *Binary) any {
+	leftValue := e.evaluate(binary.Left)
+	rightValue := e.evaluate(binary.Right)
+
+	switch binary.Op._type {
+	case COMMA:
+		return rightValue
+	case EQUAL_EQUAL:
+		return LoxBoolean{value: isEqual(leftValue, rightValue)}
+	case BANG_EQUAL:
+		return LoxBoolean{value: !isEqual(leftValue, rightValue)}
+	case PLUS:
+		return e.add(binary.Op, leftValue, rightValue)
+	case STAR:
+		return e.multiply(binary.Op, leftValue, rightValue)
+	case STAR_STAR:
+		return e.power(binary.Op, leftValue, rightValue)
+	case PLUS_PLUS, MINUS_MINUS:
+		return e.increment(binary.Op, leftValue, rightValue)
+	case GREATER, GREATER_EQUAL, LESS, LESS_EQUAL:
+		return e.compare(binary.Op, leftValue, rightValue)
+	case AMPERSAND, PIPE, CARET, LESS_LESS, GREATER_GREATER:
+		return e.bitwise(binary.Op, leftValue, rightValue)
+	}
+
+	left, right := numberOperands(binary.Op, leftValue, rightValue)
+	switch binary.Op._type {
+	case MINUS:
+		return LoxNumber{value: left - right}
+	case SLASH:
+		return LoxNumber{value: left / right}
+	default:
+		panic("unknown operator")
+	}
+}
+
+// increment is the binary form of a prefix ++ or --, which the parser
+// desugars into an assignment. Unlike +, it never concatenates.
+func (e *Evaluator) increment(op Token, leftValue, rightValue any) LoxNumber {
+	left, ok := leftValue.(LoxNumber)
+	if !ok {
+		panic(RuntimeError{Token: op, Message: "Operand must be a number."})
+	}
+	if op._type == MINUS_MINUS {
+		return LoxNumber{value: left.value - rightValue.(LoxNumber).value}
+	}
+	return LoxNumber{value: left.value + rightValue.(LoxNumber).value}
+}
+
+// add adds two numbers or concatenates two strings
+func (e *Evaluator) add(op Token, leftValue, rightValue any) any {
+	if e.LooseConcat {
+		_, leftIsString := leftValue.(LoxString)
+		_, rightIsString := rightValue.(LoxString)
+		if leftIsString || rightIsString {
+			return LoxString{value: stringify(leftValue) + stringify(rightValue)}
+		}
+	}
+
+	switch left := leftValue.(type) {
+	case LoxNumber:
+		if right, ok := rightValue.(LoxNumber); ok {
+			return LoxNumber{value: left.value + right.value}
+		}
+	case LoxString:
+		if right, ok := rightValue.(LoxString); ok {
+			return LoxString{value: left.value + right.value}
+		}
+	}
+	panic(RuntimeError{Token: op, Message: "Operands must be two numbers or two strings."})
+}
+
+// numberOperand unwraps the operand of a numeric unary operator
+func numberOperand(op Token, value any) float64 {
+	number, ok := value.(LoxNumber)
+	if !ok {
+		panic(RuntimeError{Token: op, Message: "Operand must be a number."})
+	}
+	return number.value
+}
+
+// numberOperands unwraps the operands of a numeric binary operator
+func numberOperands(op Token, leftValue, rightValue any) (float64, float64) {
+	left, ok := leftValue.(LoxNumber)
+	if ok {
+		if right, ok := rightValue.(LoxNumber); ok {
+			return left.value, right.value
+		}
+	}
+	panic(RuntimeError{Token: op, Message: "Operands must be numbers."})
+}
+
+// bitwise applies a bitwise operator to two integers
+func (e *Evaluator) bitwise(op Token, leftValue, rightValue any) LoxNumber {
+	left, right := numberOperands(op, leftValue, rightValue)
+	if !isInt64(left) || !isInt64(right) {
+		panic(RuntimeError{Token: op, Message: "Operands must be integers."})
+	}
+	a, b := int64(left), int64(right)
+
+	var result int64
+	switch op._type {
+	case AMPERSAND:
+		result = a & b
+	case PIPE:
+		result = a | b
+	case CARET:
+		result = a ^ b
+	default:
+		if b < 0 {
+			panic(RuntimeError{Token: op, Message: "Shift count must be non-negative."})
+		}
+		if op._type == LESS_LESS {
+			result = a << b
+		} else {
+			result = a >> b
+		}
+	}
+	return LoxNumber{value: float64(result)}
+}
+
+// isInt64 reports whether n is a whole number that fits in an int64
+func isInt64(n float64) bool {
+	return n == math.Trunc(n) && n >= math.MinInt64 && n < math.MaxInt64
+}
+
+// power raises left to right. A result that is not a real number, such as
+// (-1) ** 0.5, is an error rather than NaN.
+func (e *Evaluator) power(op Token, leftValue, rightValue any) LoxNumber {
+	left, right := numberOperands(op, leftValue, rightValue)
+	value, err := realResult("**", math.Pow(left, right), left, right)
+	if err != nil {
+		panic(RuntimeError{Token: op, Message: err.Error()})
+	}
+	return LoxNumber{value: value}
+}
+
+// compare orders two numbers numerically or two strings lexicographically.
+// The book only allows numbers; strings are supported here as well and
+// compare by byte, so by code point. Any other pairing, including a number
+// with a string, is an error. Comparisons with NaN (0/0) are always false.
+func (e *Evaluator) compare(op Token, leftValue, rightValue any) LoxBoolean {
+	switch left := leftValue.(type) {
+	case LoxNumber:
+		if right, ok := rightValue.(LoxNumber); ok {
+			return LoxBoolean{value: ordered(op, left.value, right.value)}
+		}
+	case LoxString:
+		if right, ok := rightValue.(LoxString); ok {
+			return LoxBoolean{value: ordered(op, left.value, right.value)}
+		}
+	}
+	panic(RuntimeError{Token: op, Message: "Operands must be two numbers or two strings."})
+}
+
+func ordered[T float64 | string](op Token, left, right T) bool {
+	switch op._type {
+	case GREATER:
+		return left > right
+	case GREATER_EQUAL:
+		return left >= right
+	case LESS:
+		return left < right
+	default:
+		return left <= right
+	}
+}
+
+// multiply multiplies two numbers, or repeats a string a number of times
+func (e *Evaluator) multiply(op Token, leftValue, rightValue any) any {
+	switch left := leftValue.(type) {
+	case LoxNumber:
+		switch right := rightValue.(type) {
+		case LoxNumber:
+			return LoxNumber{value: left.value * right.value}
+		case LoxString:
+			return repeat(op, right, left)
+		}
+	case LoxString:
+		if right, ok := rightValue.(LoxNumber); ok {
+			return repeat(op, left, right)
+		}
+	}
+	panic(RuntimeError{Token: op, Message: "Operands must be two numbers or a string and a number."})
+}
+
+// maxRepeatLength caps the length of a repeated string, so a huge count is
+// an error rather than running out of memory
+const maxRepeatLength = 10_000_000
+
+func repeat(op Token, text LoxString, count LoxNumber) LoxString {
+	if count.value < 0 || count.value != math.Trunc(count.value) {
+		panic(RuntimeError{Token: op, Message: "String repetition count must be a non-negative integer."})
+	}
+	if text.value == "" {
+		return text
+	}
+	if float64(len(text.value))*count.value > maxRepeatLength {
+		panic(RuntimeError{Token: op, Message: fmt.Sprintf("String repetition result is longer than %d bytes.", maxRepeatLength)})
+	}
+	return LoxString{value: strings.Repeat(text.value, int(count.value))}
+}
+
+func (e *Evaluator) VisitInterpolationExpr(interpolation *Interpolation) any {
+	var text strings.Builder
+	for _, part := range interpolation.Parts {
+		text.WriteString(stringify(e.evaluate(part)))
+	}
+	return LoxString{value: text.String()}
+}
+
+func (e *Evaluator) VisitGroupingExpr(grouping *Grouping) any {
+	return e.evaluate(grouping.Expression)
+}
+
+func (e *Evaluator) VisitUnaryExpr(unary *Unary) any {
+	right := e.evaluate(unary.Right)
+
+	switch unary.Op._type {
+	case BANG:
+		return LoxBoolean{value: !isTruthy(right)}
+	case MINUS:
+		return LoxNumber{value: -numberOperand(unary.Op, right)}
+	default:
+		panic("unknown operator")
+	}
+}
+
+func (e *Evaluator) VisitLogicalExpr(logical *Logical) any {
+	left := e.evaluate(logical.Left)
+
+	if logical.Op._type == OR {
+		if isTruthy(left) {
+			return left
+		}
+	} else if !isTruthy(left) {
+		return left
+	}
+	return e.evaluate(logical.Right)
+}
+
+func (e *Evaluator) VisitConditionalExpr(conditional *Conditional) any {
+	if isTruthy(e.evaluate(conditional.Condition)) {
+		return e.evaluate(conditional.ThenBranch)
+	}
+	return e.evaluate(conditional.ElseBranch)
+}
+
+func (e *Evaluator) VisitGetExpr(get *Get) any {
+	object := e.evaluate(get.Object)
+	switch object := object.(type) {
+	case *LoxInstance:
+		return object.get(e, get.Name)
+	case *LoxClass:
+		return object.get(e, get.Name)
+	}
+	panic(RuntimeError{Token: get.Name, Message: "Only instances have properties."})
+}
+
+func (e *Evaluator) VisitSetExpr(set *Set) any {
+	object := e.evaluate(set.Object)
+	instance, ok := object.(*LoxInstance)
+	if !ok {
+		panic(RuntimeError{Token: set.Name, Message: "Only instances have fields."})
+	}
+
+	value := e.evaluate(set.Value)
+	instance.set(set.Name, value)
+	return value
+}
+
+func (e *Evaluator) VisitArrayLiteralExpr(array *ArrayLiteral) any {
+	elements := make([]any, len(array.Elements))
+	for i, element := range array.Elements {
+		elements[i] = e.evaluate(element)
+	}
+	return &LoxArray{elements: elements}
+}
+
+func (e *Evaluator) VisitMapLiteralExpr(literal *MapLiteral) any {
+	m := &LoxMap{entries: make(map[any]any, len(literal.Keys))}
+	for i, key := range literal.Keys {
+		m.set(literal.Brace, e.evaluate(key), e.evaluate(literal.Values[i]))
+	}
+	return m
+}
+
+func (e *Evaluator) VisitIndexExpr(index *Index) any {
+	switch object := e.evaluate(index.Object).(type) {
+	case *LoxArray:
+		return object.get(index.Bracket, e.evaluate(index.Index))
+	case *LoxMap:
+		return object.get(index.Bracket, e.evaluate(index.Index))
+	}
+	panic(RuntimeError{Token: index.Bracket, Message: "Only arrays and maps can be indexed."})
+}
+
+func (e *Evaluator) VisitIndexAssignExpr(assign *IndexAssign) any {
+	switch object := e.evaluate(assign.Object).(type) {
+	case *LoxArray:
+		index := e.evaluate(assign.Index)
+		value := e.evaluate(assign.Value)
+		object.set(assign.Bracket, index, value)
+		return value
+	case *LoxMap:
+		key := e.evaluate(assign.Index)
+		value := e.evaluate(assign.Value)
+		object.set(assign.Bracket, key, value)
+		return value
+	}
+	panic(RuntimeError{Token: assign.Bracket, Message: "Only arrays and maps can be indexed."})
+}
+
+func (e *Evaluator) VisitThisExpr(this *This) any {
+	return e.lookUpVariable(this.Keyword, this)
+}
+
+func (e *Evaluator) VisitSuperExpr(super *Super) any {
+	distance := e.locals[super]
+	superclass := e.environment.getAt(distance, "super").(*LoxClass)
+
+	// "this" is always in the scope just inside the one defining "super"
+	object := e.environment.getAt(distance-1, "this").(*LoxInstance)
+
+	method := superclass.findMethod(super.Method.lexeme)
+	if method == nil {
+		panic(RuntimeError{Token: super.Method, Message: fmt.Sprintf("Undefined property '%s'.", super.Method.lexeme)})
+	}
+	return method.bind(object)
+}
+
+func (e *Evaluator) VisitFunctionExpr(function *FunctionExpr) any {
+	return &LoxFunction{declaration: function.Declaration, closure: e.environment}
+}
+
+func (e *Evaluator) VisitVariableExpr(variable *Variable) any {
+	return e.lookUpVariable(variable.Name, variable)
+}
+
+func (e *Evaluator) lookUpVariable(name Token, expr Expr) any {
+	var value any
+	if distance, ok := e.locals[expr]; ok {
+		value = e.environment.getAt(distance, name.lexeme)
+	} else {
+		var err error
+		if value, err = e.globals.get(name); err != nil {
+			panic(err)
+		}
+	}
+	if _, ok := value.(uninitialized); ok {
+		panic(RuntimeError{Token: name, Message: fmt.Sprintf("Variable '%s' used before assignment.", name.lexeme)})
+	}
+	return value
+}
+
+func (e *Evaluator) VisitAssignExpr(assign *Assign) any {
+	value := e.evaluate(assign.Value)
+	if distance, ok := e.locals[assign]; ok {
+		e.environment.assignAt(distance, assign.Name, value)
+	} else if err := e.globals.assign(assign.Name, value); err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func (e *Evaluator) VisitCallExpr(call *Call) any {
+	callee := e.evaluate(call.Callee)
+
+	var arguments []any
+	for _, argument := range call.Arguments {
+		arguments = append(arguments, e.evaluate(argument))
+	}
+
+	function, ok := callee.(LoxCallable)
+	if !ok {
+		panic(RuntimeError{Token: call.Paren, Message: "Can only call functions and classes."})
+	}
+	if native, ok := function.(*NativeFunction); ok && native.maxArity > native.arity {
+		if !native.acceptsArguments(len(arguments)) {
+			panic(RuntimeError{Token: call.Paren, Message: fmt.Sprintf(
+				"Expected %d to %d arguments but got %d.", native.arity, native.maxArity, len(arguments))})
+		}
+	} else if len(arguments) != function.Arity() {
+		panic(RuntimeError{Token: call.Paren, Message: fmt.Sprintf(
+			"Expected %d arguments but got %d.", function.Arity(), len(arguments))})
+	}
+	return e.call(function, call.Paren, arguments)
+}
+
+// isTruthy follows Ruby's rule: nil and false are falsey, the rest truthy
+func isTruthy(value any) bool {
+	switch v := value.(type) {
+	case LoxNil:
+		return false
+	case LoxBoolean:
+		return v.value
+	default:
+		return true
+	}
+}
+
+// isEqual is Lox equality, which never fails. Values of different types are
+// never equal, primitives compare by value (so NaN != NaN, as in IEEE 754)
+// and everything else, like arrays and instances, by identity.
+func isEqual(a, b any) bool {
+	switch a := a.(type) {
+	case LoxNil:
+		_, ok := b.(LoxNil)
+		return ok
+	case LoxBoolean:
+		b, ok := b.(LoxBoolean)
+		return ok && a.value == b.value
+	case LoxNumber:
+		b, ok := b.(LoxNumber)
+		return ok && a.value == b.value
+	case LoxString:
+		b, ok := b.(LoxString)
+		return ok && a.value == b.value
+	}
+	// Go's == panics on uncomparable types, which have no identity to test
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// stringify formats a runtime value the way Lox prints it. It is the only
+// place values are turned into text, so print, the REPL, evaluate and string
+// coercion all agree. Numbers print without a trailing ".0" and never in
+// exponent form, infinities and NaN use their Lox spellings.
+func stringify(value any) string {
+	switch v := value.(type) {
+	case LoxNumber:
+		switch {
+		case math.IsNaN(v.value):
+			return "NaN"
+		case math.IsInf(v.value, 1):
+			return "Infinity"
+		case math.IsInf(v.value, -1):
+			return "-Infinity"
+		}
+		return strconv.FormatFloat(v.value, 'f', -1, 64)
+	case LoxLiteral:
+		return v.RawPrint()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		panic("cannot stringify value")
+	}
+}
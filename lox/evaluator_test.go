@@ -0,0 +1,742 @@
+package lox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterpolation(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{`var name = "Lox"; print "Hello, ${name}!";`, "Hello, Lox!"},
+		{`print "${1 + 2} and ${true}";`, "3 and true"},
+		{`print "a ${"b ${1 + 1} c"} d";`, "a b 2 c d"},
+		{`print "a ${ {"k": "v"}["k"] } b";`, "a v b"},
+		{`print "\${not} ${"spliced"}";`, "${not} spliced"},
+	}
+	for _, test := range tests {
+		expectOutput(t, test.source, test.want+"\n")
+	}
+}
+
+func TestEmptyInterpolation(t *testing.T) {
+	expectError(t, `print "${}";`, ExitSyntaxError,
+		"[line 1] Error at '}\"': Expect expression between '${' and '}'.\n"+
+			"print \"${}\";\n"+
+			"         ^\n")
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"recursion", `
+			fun fib(n) {
+				if (n < 2) return n;
+				return fib(n - 1) + fib(n - 2);
+			}
+			print fib(20);`, "6765"},
+		{"early return in loop", `
+			fun find(limit) {
+				for (var i = 0; i < limit; i = i + 1) {
+					while (true) {
+						if (i * i > 20) return i;
+						break;
+					}
+				}
+				return -1;
+			}
+			print find(100);
+			print find(3);`, "5\n-1"},
+		{"bare return", `fun f() { return; } print f();`, "nil"},
+		{"no return", `fun f() { 1; } print f();`, "nil"},
+		{"prints name", `fun greet() {} print greet;`, "<fn greet>"},
+		{"arguments", `fun add(a, b, c) { return a + b + c; } print add(1, 2, 3);`, "6"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestTooManyParameters(t *testing.T) {
+	params := "p0"
+	for i := 1; i <= 255; i++ {
+		params += ", p" + strconv.Itoa(i)
+	}
+	_, stderr, code := run(t, "fun f("+params+") {}")
+	if code != ExitSyntaxError || !strings.Contains(stderr, "Error at 'p255': Can't have more than 255 parameters.") {
+		t.Errorf("256 parameters exited %d, reporting:\n%s", code, stderr)
+	}
+}
+
+func TestClosures(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"counter", `
+			fun makeCounter() {
+				var i = 0;
+				fun count() {
+					i = i + 1;
+					print i;
+				}
+				return count;
+			}
+			var counter = makeCounter();
+			counter();
+			counter();`, "1\n2"},
+		{"independent counters", `
+			fun makeCounter() {
+				var i = 0;
+				fun count() { i = i + 1; return i; }
+				return count;
+			}
+			var a = makeCounter();
+			var b = makeCounter();
+			a(); a();
+			print a();
+			print b();`, "3\n1"},
+		{"loop variable", `
+			var fns = [];
+			for (var i = 0; i < 3; i = i + 1) {
+				var j = i;
+				fun f() { return j; }
+				push(fns, f);
+			}
+			print fns[0]() + fns[1]() + fns[2]();`, "3"},
+		{"block local after the block", `
+			var f;
+			{
+				var local = "kept";
+				fun g() { return local; }
+				f = g;
+			}
+			print f();`, "kept"},
+		{"defining scope, not calling scope", `
+			var x = "global";
+			fun show() { return x; }
+			fun caller() { var x = "caller"; return show(); }
+			print caller();`, "global"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestComma(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{"print (1, 2, 3);", "3"},
+		{"var a = 0; var b = (a = 1, a + 1); print a; print b;", "1\n2"},
+		{"fun f(a, b) { return a - b; } print f(5, 2);", "3"},
+		{"fun f(a, b) { return b; } print f((1, 2), 3);", "3"},
+		{"print [1, 2, 3][1];", "2"},
+	}
+	for _, test := range tests {
+		expectOutput(t, test.source, test.want+"\n")
+	}
+}
+
+func TestConditional(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{`print true ? "yes" : "no";`, "yes"},
+		{`print nil ? "yes" : "no";`, "no"},
+		{`var n = 5; print n < 0 ? "neg" : n == 0 ? "zero" : "pos";`, "pos"},
+		{`print (false ? 1 : true) ? 2 : 3;`, "2"},
+		{`var a; a = true ? 1 : 2; print a;`, "1"},
+		{`fun boom() { print "evaluated"; return 0; } print true ? "taken" : boom();`, "taken"},
+		{`fun boom() { print "evaluated"; return 0; } print false ? boom() : "taken";`, "taken"},
+	}
+	for _, test := range tests {
+		expectOutput(t, test.source, test.want+"\n")
+	}
+}
+
+func TestConditionalMissingColon(t *testing.T) {
+	_, stderr, code := run(t, "print true ? 1;\n")
+	if code != ExitSyntaxError || !strings.HasPrefix(stderr, "[line 1] Error at ';': Expect ':' after then branch of conditional expression.") {
+		t.Errorf("missing ':' exited %d, reporting:\n%s", code, stderr)
+	}
+}
+
+func TestPlus(t *testing.T) {
+	expectOutput(t, `print 1 + 2; print "a" + "b";`, "3\nab\n")
+	for _, source := range []string{`1 + "a"`, `"a" + 1`, `nil + 1`, `true + "a"`} {
+		expectError(t, "print "+source+";", ExitRuntimeError,
+			"Operands must be two numbers or two strings.\n[line 1] in script\n")
+	}
+}
+
+func TestStringRepetition(t *testing.T) {
+	expectOutput(t, `print "ab" * 3; print 3 * "ab"; print "ab" * 0; print 2 * 3;`, "ababab\nababab\n\n6\n")
+	for _, count := range []string{"-1", "1.5"} {
+		expectError(t, `print "ab" * `+count+`;`, ExitRuntimeError,
+			"String repetition count must be a non-negative integer.\n[line 1] in script\n")
+	}
+	expectOutput(t, `print "[" + "-" * 5 + "]"; print "a" + 2 * "b" + "c";`, "[-----]\nabbc\n")
+	expectError(t, `print "ab" * 1e7;`, ExitRuntimeError,
+		"String repetition result is longer than 10000000 bytes.\n[line 1] in script\n")
+}
+
+func TestStringComparison(t *testing.T) {
+	expectOutput(t, `
+		print "apple" < "banana";
+		print "b" > "a";
+		print "abc" <= "abc";
+		print "ab" >= "abc";
+		print 2 < 10;
+		print "2" < "10";`, "true\ntrue\ntrue\nfalse\ntrue\nfalse\n")
+	expectError(t, `print "a" < 1;`, ExitRuntimeError, "Operands must be two numbers or two strings.\n[line 1] in script\n")
+}
+
+func TestBreak(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"while", `var i = 0; while (true) { if (i == 3) break; print i; i = i + 1; }`, "0\n1\n2"},
+		{"nested block", `for (var i = 0; i < 10; i = i + 1) { { if (i == 2) { break; } } print i; }`, "0\n1"},
+		{"inner loop only", `
+			for (var i = 0; i < 3; i = i + 1) {
+				for (var j = 0; j < 3; j = j + 1) {
+					if (j == 1) break;
+					print i + j * 10;
+				}
+			}`, "0\n1\n2"},
+		{"skips the increment", `var i; for (i = 0; i < 5; i = i + 1) { if (i == 2) break; } print i;`, "2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestBreakOutsideLoop(t *testing.T) {
+	expectError(t, "break;", ExitSyntaxError, "[line 1] Error at 'break': Must be inside a loop or switch to use 'break'.\n")
+	// A function body is not inside the loop that declares it
+	expectError(t, "while (true) { fun f() { break; } }", ExitSyntaxError,
+		"[line 1] Error at 'break': Must be inside a loop or switch to use 'break'.\n")
+	expectError(t, "switch (1) { case 1: fun f() { break; } }", ExitSyntaxError,
+		"[line 1] Error at 'break': Must be inside a loop or switch to use 'break'.\n")
+	// continue still needs a loop
+	expectError(t, "switch (1) { case 1: continue; }", ExitSyntaxError,
+		"[line 1] Error at 'continue': Must be inside a loop to use 'continue'.\n")
+}
+
+func TestStackTrace(t *testing.T) {
+	expectError(t, "print -nil;", ExitRuntimeError, "Operand must be a number.\n[line 1] in script\n")
+	expectError(t, `fun inner() {
+  return -nil;
+}
+fun outer() {
+  return inner();
+}
+print outer();`, ExitRuntimeError, `Operand must be a number.
+[line 2] in fn inner()
+[line 5] in fn outer()
+[line 7] in script
+`)
+	expectError(t, "fun f() { len(1); }\nf();", ExitRuntimeError, `Argument to len() must be a string, an array or a map.
+[line 1] in native len()
+[line 1] in fn f()
+[line 2] in script
+`)
+	// Calls that returned, normally or by return, leave no stale frames
+	expectError(t, `fun early() { if (true) return 1; }
+fun late() { return 2; }
+early();
+late();
+print -nil;`, ExitRuntimeError, "Operand must be a number.\n[line 5] in script\n")
+	expectError(t, `fun thrower() { throw "x"; }
+try { thrower(); } catch (e) {}
+print -nil;`, ExitRuntimeError, "Operand must be a number.\n[line 3] in script\n")
+}
+
+func TestStackTraceElided(t *testing.T) {
+	_, stderr, _ := run(t, "fun f(n) { if (n == 0) return -nil; return f(n - 1); }\nprint f(30);")
+	frame := "[line 1] in fn f()\n"
+	want := "Operand must be a number.\n" + strings.Repeat(frame, traceEnds) +
+		"... 11 more calls\n" + strings.Repeat(frame, traceEnds) + "[line 2] in script\n"
+	if stderr != want {
+		t.Errorf("run reported:\n%s\nwant:\n%s", stderr, want)
+	}
+}
+
+func TestContinue(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"for runs the increment", `for (var i = 0; i < 5; i = i + 1) { if (i == 2) continue; print i; }`, "0\n1\n3\n4"},
+		{"while skips the rest of the body", `var i = 0; while (i < 5) { i = i + 1; if (i == 2) continue; print i; }`, "1\n3\n4\n5"},
+		{"inner loop only", `
+			for (var i = 0; i < 2; i = i + 1) {
+				for (var j = 0; j < 3; j = j + 1) {
+					if (j == 1) continue;
+					print i * 10 + j;
+				}
+			}`, "0\n2\n10\n12"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestContinueOutsideLoop(t *testing.T) {
+	expectError(t, "continue;", ExitSyntaxError, "[line 1] Error at 'continue': Must be inside a loop to use 'continue'.\n")
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"through blocks and loops", `
+			try {
+				for (var i = 0; i < 3; i = i + 1) {
+					{ if (i == 1) throw i; }
+					print i;
+				}
+			} catch (e) { print e; }`, "0\n1"},
+		{"through calls", `
+			fun inner() { throw "deep"; }
+			fun outer() { inner(); print "skipped"; }
+			try { outer(); } catch (e) { print e; }
+			print "after";`, "deep\nafter"},
+		{"any value", `
+			class Problem { init(code) { this.code = code; } }
+			try { throw Problem(42); } catch (e) { print e.code; }
+			try { throw nil; } catch (e) { print e; }`, "42\nnil"},
+		{"interpreter errors", `
+			try { print undefined; } catch (e) { print e; }
+			try { print 1 + "a"; } catch (e) { print e; }`,
+			"Undefined variable 'undefined'.\nOperands must be two numbers or two strings."},
+		{"catch scope", `
+			var e = "outer";
+			try { throw "inner"; } catch (e) { print e; }
+			print e;`, "inner\nouter"},
+		{"rethrow", `
+			try {
+				try { throw "first"; } catch (e) { throw e + " again"; }
+			} catch (e) { print e; }`, "first again"},
+		{"finally on normal exit", `
+			try { print "body"; } finally { print "finally"; }`, "body\nfinally"},
+		{"finally on throw", `
+			try {
+				try { throw "x"; } finally { print "finally"; }
+			} catch (e) { print "caught " + e; }`, "finally\ncaught x"},
+		{"finally after catch", `
+			try { throw "x"; } catch (e) { print e; } finally { print "finally"; }`, "x\nfinally"},
+		{"finally on return", `
+			fun f() {
+				try { return "returned"; } finally { print "finally"; }
+			}
+			print f();`, "finally\nreturned"},
+		{"finally on break", `
+			while (true) {
+				try { break; } finally { print "finally"; }
+			}
+			print "out";`, "finally\nout"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestUncaughtThrow(t *testing.T) {
+	stdout, stderr, code := run(t, `print "before"; if (true) throw "boom"; print "after";`)
+	if code != ExitRuntimeError || stdout != "before\n" || stderr != "boom\n[line 1] in script\n" {
+		t.Errorf("uncaught throw exited %d printing %q, reporting:\n%s", code, stdout, stderr)
+	}
+	expectError(t, `try { throw 1 + 2; } finally { print "finally"; }`, ExitRuntimeError,
+		"3\n[line 1] in script\n")
+}
+
+func TestLambdas(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"argument", `
+			fun thrice(fn) { for (var i = 1; i <= 3; i = i + 1) fn(i); }
+			thrice(fun (i) { print i; });`, "1\n2\n3"},
+		{"immediately invoked", `print (fun (a, b) { return a + b; })(1, 2);`, "3"},
+		{"closure", `
+			fun counter() { var n = 0; return fun () { n = n + 1; return n; }; }
+			var c = counter();
+			c();
+			print c();`, "2"},
+		{"printed", `print fun () {};`, "<fn>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestLambdaStatement(t *testing.T) {
+	// A statement starting with fun is a declaration, so it needs a name
+	expectError(t, "fun () {};", ExitSyntaxError, "[line 1] Error at 'fun': Expect function name.\nfun () {};\n^\n")
+	expectError(t, "(fun f() {});", ExitSyntaxError, "[line 1] Error at 'f': Expect '(' after 'fun'.\n(fun f() {});\n     ^\n")
+}
+
+func TestOut(t *testing.T) {
+	if evaluator := NewEvaluator(); evaluator.Out != os.Stdout {
+		t.Errorf("NewEvaluator().Out = %v, want os.Stdout", evaluator.Out)
+	}
+
+	var out bytes.Buffer
+	interpreter := NewInterpreter()
+	interpreter.Out = &out
+	if code := interpreter.Run(`print "one"; write(2); print 3; print nil;`); code != ExitOK {
+		t.Fatalf("Run exited %d", code)
+	}
+	if want := "one\n23\nnil\n"; out.String() != want {
+		t.Errorf("Out = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPower(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{"print 2 ** 3;", "8"},
+		{"print 2 ** 3 ** 2;", "512"},
+		{"print (2 ** 3) ** 2;", "64"},
+		{"print -2 ** 2;", "-4"},
+		{"print (-2) ** 2;", "4"},
+		{"print 2 ** -1;", "0.5"},
+		{"print 2 * 3 ** 2;", "18"},
+		{"print 0 ** 0;", "1"},
+	}
+	for _, test := range tests {
+		expectOutput(t, test.source, test.want+"\n")
+	}
+	expectError(t, `print "a" ** 2;`, ExitRuntimeError, "Operands must be numbers.\n[line 1] in script\n")
+	expectError(t, "print (-8) ** (1/3);", ExitRuntimeError, "Result of ** is not a real number.\n[line 1] in script\n")
+	expectError(t, "print (-1) ** 0.5;", ExitRuntimeError, "Result of ** is not a real number.\n[line 1] in script\n")
+	// With a space between them, the stars are two multiplications, not **
+	expectError(t, "var a = 2; var b = 3; print a * *b;", ExitSyntaxError,
+		"[line 1] Error at '*': Expect expression.\nvar a = 2; var b = 3; print a * *b;\n                                ^\n")
+}
+
+func TestDoWhile(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"runs once", `var i = 0; do { print i; } while (false);`, "0"},
+		{"loops", `var i = 0; do { print i; i = i + 1; } while (i < 3);`, "0\n1\n2"},
+		{"break", `var i = 0; do { i = i + 1; if (i == 2) break; print i; } while (true);`, "1"},
+		{"continue checks the condition", `var i = 0; do { i = i + 1; if (i == 2) continue; print i; } while (i < 4);`, "1\n3\n4"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestDoWhileErrors(t *testing.T) {
+	expectParseErrors(t, "do print 1;", "[line 1] Error at end: Expect 'while' after do-while body.")
+	expectParseErrors(t, "do print 1; while (false)", "[line 1] Error at end: Expect ';' after do-while condition.")
+}
+
+func TestSwitch(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"first case", `switch (1) { case 1: print "one"; case 2: print "two"; }`, "one"},
+		{"no fallthrough", `switch (1) { case 1: print "a"; print "b"; case 2: print "c"; }`, "a\nb"},
+		{"no match", `switch (3) { case 1: print "one"; } print "after";`, "after"},
+		{"default", `switch (3) { case 1: print "one"; default: print "other"; }`, "other"},
+		{"expression case", `var x = 2; switch (x * 2) { case 1 + 3: print "four"; default: print "other"; }`, "four"},
+		{"lox equality", `switch ("a") { case 1: print "number"; case "a": print "string"; }`, "string"},
+		{"break", `switch (1) { case 1: print "a"; if (true) break; print "b"; } print "after";`, "a\nafter"},
+		{"break default", `switch (3) { default: print "a"; if (true) break; print "b"; } print "after";`, "a\nafter"},
+		{"break in loop", `for (var i = 0; i < 3; i = i + 1) { switch (i) { case 1: break; } print i; }`, "0\n1\n2"},
+		{"loop in arm", `switch (1) { case 1: while (true) break; print "a"; }`, "a"},
+		{"continue in loop", `for (var i = 0; i < 3; i = i + 1) { switch (i) { case 1: continue; } print i; }`, "0\n2"},
+		{"subject once", `
+			var n = 0;
+			fun next() { n = n + 1; return n; }
+			switch (next()) { case 2: print "two"; case 1: print "one"; }
+			print n;`, "one\n1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestSwitchErrors(t *testing.T) {
+	expectParseErrors(t, "switch (1) { default: print 1; case 1: print 2; }",
+		"[line 1] Error at 'case': Can't have a case after the default case.")
+	expectParseErrors(t, "switch (1) { case 1: print 1;", "[line 1] Error at end: Expect '}' after switch cases.")
+}
+
+func TestLambdaExpressions(t *testing.T) {
+	expectOutput(t, `var add = fun (a, b) { return a + b; }; print add(1, 2);`, "3\n")
+	expectOutput(t, `print fun (n) { return n * 2; }(21);`, "42\n")
+	expectOutput(t, `fun apply(f, x) { return f(x); } print apply(fun (x) { return x + 1; }, 1);`, "2\n")
+}
+
+func TestOperandTypes(t *testing.T) {
+	values := []string{"1", `"a"`, "true", "nil"}
+	// Every operator accepts two numbers, these accept some other pairs
+	messages := map[string]string{
+		"-": "Operands must be numbers.",
+		"/": "Operands must be numbers.",
+		"*": "Operands must be two numbers or a string and a number.",
+		"<": "Operands must be two numbers or two strings.",
+		">": "Operands must be two numbers or two strings.",
+		"+": "Operands must be two numbers or two strings.",
+	}
+	accepts := func(op, left, right string) bool {
+		switch {
+		case left == "1" && right == "1":
+			return true
+		case op == "*":
+			return left == "1" && right == `"a"` || left == `"a"` && right == "1"
+		case op == "<" || op == ">" || op == "+":
+			return left == `"a"` && right == `"a"`
+		}
+		return false
+	}
+	for op, message := range messages {
+		for _, left := range values {
+			for _, right := range values {
+				source := "print " + left + " " + op + " " + right + ";"
+				_, stderr, code := run(t, source)
+				if accepts(op, left, right) {
+					if code != ExitOK {
+						t.Errorf("run(%q) exited %d, reporting:\n%s", source, code, stderr)
+					}
+				} else if want := message + "\n[line 1] in script\n"; code != ExitRuntimeError || stderr != want {
+					t.Errorf("run(%q) exited %d, reporting:\n%s\nwant %d, reporting:\n%s", source, code, stderr, ExitRuntimeError, want)
+				}
+			}
+		}
+	}
+	for _, value := range values[1:] {
+		expectError(t, "print -"+value+";", ExitRuntimeError, "Operand must be a number.\n[line 1] in script\n")
+	}
+	expectOutput(t, "print 6 / 4; print 1 / 0; print 2 - 3;", "1.5\nInfinity\n-1\n")
+}
+
+func TestConcatenation(t *testing.T) {
+	expectOutput(t, `print "" + ""; print "a" + ""; print "" + "b";`, "\na\nb\n")
+	expectOutput(t, `var s = ""; for (var i = 0; i < 5; i = i + 1) s = s + "ab"; print s;`, "ababababab\n")
+	expectError(t, "var s = \"x\";\nprint s + 1;", ExitRuntimeError,
+		"Operands must be two numbers or two strings.\n[line 2] in script\n")
+}
+
+func TestLooseConcat(t *testing.T) {
+	var out bytes.Buffer
+	interpreter := NewInterpreter()
+	interpreter.Out = &out
+	interpreter.LooseConcat = true
+	code := interpreter.Run(`print "count: " + 3; print 1.5 + "!"; print "is " + nil + " " + true; print 1 + 2;`)
+	if code != ExitOK {
+		t.Fatalf("Run exited %d", code)
+	}
+	if want := "count: 3\n1.5!\nis nil true\n3\n"; out.String() != want {
+		t.Errorf("Run printed %q, want %q", out.String(), want)
+	}
+}
+
+func TestComparison(t *testing.T) {
+	expectOutput(t, "print 1 < 2; print 2 <= 2; print 1 > 2; print 2 >= 3;", "true\ntrue\nfalse\nfalse\n")
+	expectOutput(t, "print (1 + 2) > (4 - 3); print -1 < 0 == true;", "true\ntrue\n")
+	// NaN is unordered, so every comparison with it is false
+	expectOutput(t, "print 0/0 < 1; print 0/0 >= 1; print 1 <= 0/0;", "false\nfalse\nfalse\n")
+	// Unlike the book, two strings compare; a string and a number don't
+	expectOutput(t, `print "a" < "b";`, "true\n")
+	for _, source := range []string{`"a" < 1`, `1 >= "a"`, `nil > 1`, `true <= false`} {
+		expectError(t, "print "+source+";", ExitRuntimeError,
+			"Operands must be two numbers or two strings.\n[line 1] in script\n")
+	}
+}
+
+func TestEquality(t *testing.T) {
+	// Each value is equal to itself and to nothing else in the list
+	values := []string{"nil", "false", "true", "0", "1", `""`, `"0"`, `"a"`}
+	for i, left := range values {
+		for j, right := range values {
+			want := strconv.FormatBool(i == j)
+			expectOutput(t, "print "+left+" == "+right+";", want+"\n")
+			expectOutput(t, "print "+left+" != "+right+";", strconv.FormatBool(i != j)+"\n")
+		}
+	}
+	// Arrays, maps, instances and functions are equal only to themselves
+	expectOutput(t, `
+		class A {}
+		var a = [1]; var m = {}; var i = A();
+		print a == a; print [1] == [1];
+		print m == m; print {} == {};
+		print i == i; print A() == A();
+		print clock == clock; print A == A;`, "true\nfalse\ntrue\nfalse\ntrue\nfalse\ntrue\ntrue\n")
+	// NaN is not equal to anything, itself included
+	expectOutput(t, "var n = 0/0; print n == n; print n != n;", "false\ntrue\n")
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	expectOutput(t, "var x = 1; x += 2; print x; x -= 1; print x; x *= 5; print x; x /= 2; print x;", "3\n2\n10\n5\n")
+	expectOutput(t, `var s = "a"; s += "b"; print s;`, "ab\n")
+	expectOutput(t, "class A {} var a = A(); a.n = 1; a.n += 2; print a.n;", "3\n")
+	expectOutput(t, "var l = [1]; l[0] *= 3; print l;", "[3]\n")
+	expectOutput(t, "var x = 1; print x += 1;", "2\n")
+	for _, op := range []string{"+=", "-=", "*=", "/="} {
+		expectParseErrors(t, "1 "+op+" 2;", "[line 1] Error at '"+op+"': Invalid assignment target.")
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	expectOutput(t, "for (var i = 0; i < 3; ++i) print i;", "0\n1\n2\n")
+	expectOutput(t, "var x = 5; print --x; print x; print ++x;", "4\n4\n5\n")
+	expectOutput(t, "class A {} var a = A(); a.n = 1; ++a.n; print a.n; var l = [1]; --l[0]; print l;", "2\n[0]\n")
+	for _, source := range []string{`var s = "a"; ++s;`, "var x = nil; --x;", "var b = true; ++b;"} {
+		expectError(t, source, ExitRuntimeError, "Operand must be a number.\n[line 1] in script\n")
+	}
+	expectParseErrors(t, "++1;", "[line 1] Error at '++': Invalid increment target.")
+}
+
+func TestIncrementLooseConcat(t *testing.T) {
+	// ++ is numeric even when + would concatenate
+	var errs bytes.Buffer
+	interpreter := NewInterpreter()
+	interpreter.Err = &errs
+	interpreter.LooseConcat = true
+	if code := interpreter.Run(`var s = "a"; ++s;`); code != ExitRuntimeError {
+		t.Errorf("Run exited %d, want %d", code, ExitRuntimeError)
+	}
+	if want := "Operand must be a number.\n[line 1] in script\n"; errs.String() != want {
+		t.Errorf("Run reported %q, want %q", errs.String(), want)
+	}
+}
+
+func TestBitwise(t *testing.T) {
+	expectOutput(t, "print 6 & 3; print 6 | 3; print 6 ^ 3; print 1 << 4; print 256 >> 2; print -8 >> 1;",
+		"2\n7\n5\n16\n64\n-4\n")
+	expectError(t, "print 1.5 & 2;", ExitRuntimeError, "Operands must be integers.\n[line 1] in script\n")
+	expectError(t, "print 2 | 0.5;", ExitRuntimeError, "Operands must be integers.\n[line 1] in script\n")
+	expectError(t, `print 1 | "a";`, ExitRuntimeError, "Operands must be numbers.\n[line 1] in script\n")
+	expectError(t, "print 1 << -1;", ExitRuntimeError, "Shift count must be non-negative.\n[line 1] in script\n")
+}
+
+func TestStringify(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{"nil", "nil"},
+		{"true", "true"},
+		{`"text"`, "text"},
+		{"4 - 3", "1"},
+		{"200.00", "200"},
+		{"10.5", "10.5"},
+		{"-0", "-0"},
+		{"0.1 + 0.2", "0.30000000000000004"},
+		{"1e21", "1000000000000000000000"},
+		{"1e-7", "0.0000001"},
+		{"1 / 0", "Infinity"},
+		{"-1 / 0", "-Infinity"},
+	}
+	for _, test := range tests {
+		// print, the REPL and string() all share stringify
+		expectOutput(t, "print "+test.source+";", test.want+"\n")
+		expectOutput(t, "print string("+test.source+");", test.want+"\n")
+		var out bytes.Buffer
+		interpreter := NewInterpreter()
+		interpreter.Out = &out
+		interpreter.RunLine(test.source)
+		if out.String() != test.want+"\n" {
+			t.Errorf("RunLine(%q) printed %q, want %q", test.source, out.String(), test.want+"\n")
+		}
+	}
+}
+
+func TestStrict(t *testing.T) {
+	tests := []struct{ name, source, stdout, stderr string }{
+		{"assigned first", "var a; a = 1; print a;", "1\n", ""},
+		{"global", "var a; print a;", "", "Variable 'a' used before assignment.\n[line 1] in script\n"},
+		{"nested scope", "var a;\n{ { print a; } }", "", "Variable 'a' used before assignment.\n[line 2] in script\n"},
+		{"local", "{ var a;\nprint a; }", "", "Variable 'a' used before assignment.\n[line 2] in script\n"},
+		{"closure", "fun f() { var b; fun g() { return b; } return g; }\nprint f()();", "",
+			"Variable 'b' used before assignment.\n[line 1] in fn g()\n[line 2] in script\n"},
+		{"assigned in closure", "var a; fun set() { a = 2; } set(); print a;", "2\n", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out, errs bytes.Buffer
+			interpreter := NewInterpreter()
+			interpreter.Out = &out
+			interpreter.Err = &errs
+			interpreter.Strict = true
+			interpreter.Run(test.source)
+			if out.String() != test.stdout || errs.String() != test.stderr {
+				t.Errorf("Run printed %q, reporting %q; want %q, reporting %q", out.String(), errs.String(), test.stdout, test.stderr)
+			}
+		})
+	}
+	// Strictness is off by default, the REPL included
+	expectOutput(t, "var a; print a;", "nil\n")
+}
+
+func TestMaxCallDepth(t *testing.T) {
+	// Mutual recursion just under the limit, 999 calls deep, still works
+	expectOutput(t, `
+		fun even(n) { if (n == 0) return true; return odd(n - 1); }
+		fun odd(n) { if (n == 0) return false; return even(n - 1); }
+		print even(998);`, "true\n")
+
+	_, stderr, code := run(t, "fun f() {\n  f();\n}\nf();")
+	if want := "Stack overflow (max call depth 1000 exceeded).\n[line 2] in fn f()\n"; code != ExitRuntimeError || !strings.HasPrefix(stderr, want) {
+		t.Errorf("run exited %d, reporting:\n%.200s\nwant %d, reporting:\n%s...", code, stderr, ExitRuntimeError, want)
+	}
+
+	var errs bytes.Buffer
+	interpreter := NewInterpreter()
+	interpreter.Err = &errs
+	interpreter.MaxCallDepth = 10
+	interpreter.Run("fun f(n) { if (n > 0) f(n - 1); }\nf(9);\nf(10);")
+	if want := "Stack overflow (max call depth 10 exceeded).\n[line 1] in fn f()\n"; !strings.HasPrefix(errs.String(), want) ||
+		!strings.HasSuffix(errs.String(), "[line 3] in script\n") {
+		t.Errorf("Run reported:\n%s\nwant it to start with:\n%s", errs.String(), want)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	tokens, _ := Tokenize("while (true) {}")
+	parser := NewParser(tokens)
+	statements, _ := parser.Parse()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := NewEvaluator().InterpretContext(ctx, statements)
+	if !errors.Is(err, ErrInterrupted) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("InterpretContext returned %v, want ErrInterrupted", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("InterpretContext took %v to stop", elapsed)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	source := `
+		fun fib(n) { if (n < 2) return n; return fib(n - 1) + fib(n - 2); }
+		for (var i = 0; i < 10; i = i + 1) print fib(i);`
+	want, _, _ := run(t, source)
+
+	var out, errs bytes.Buffer
+	interpreter := NewInterpreter()
+	interpreter.Out = &out
+	interpreter.Err = &errs
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if code := interpreter.RunContext(ctx, source); code != ExitOK || errs.Len() != 0 {
+		t.Fatalf("RunContext exited %d, reporting:\n%s", code, errs.String())
+	}
+	if out.String() != want {
+		t.Errorf("RunContext printed:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if code := interpreter.RunContext(ctx, "while (true) {}"); code != ExitRuntimeError {
+		t.Errorf("RunContext of an endless loop exited %d, want %d", code, ExitRuntimeError)
+	}
+	if errs.String() != "Execution timed out.\n" {
+		t.Errorf("RunContext of an endless loop reported %q", errs.String())
+	}
+}
@@ -0,0 +1,28 @@
+package lox_test
+
+import (
+	"fmt"
+
+	"github.com/codecrafters-io/interpreter-starter-go/lox"
+)
+
+func ExampleTokenize() {
+	tokens, errors := lox.Tokenize("var n = 1.5;\nprint \"hi\" @")
+	for _, tok := range tokens {
+		start, end := tok.Span()
+		fmt.Println(tok.Type(), tok.Lexeme(), tok.Literal(), tok.Line(), tok.Column(), start, end)
+	}
+	for _, err := range errors {
+		fmt.Println(err)
+	}
+	// Output:
+	// VAR var <nil> 1 1 0 3
+	// IDENTIFIER n <nil> 1 5 4 5
+	// EQUAL = <nil> 1 7 6 7
+	// NUMBER 1.5 1.5 1 9 8 11
+	// SEMICOLON ; <nil> 1 12 11 12
+	// PRINT print <nil> 2 1 13 18
+	// STRING "hi" hi 2 7 19 23
+	// EOF  <nil> 2 13 25 25
+	// [line 2] Error: Unexpected character: @
+}
@@ -0,0 +1,226 @@
+package lox
+
+// Expr is the base interface for all expression types
+type Expr interface {
+	Accept(visitor Visitor) any
+}
+
+// Visitor interface with methods for each expression type
+type Visitor interface {
+	VisitLiteralExpr(literal *Literal) any
+	VisitBinaryExpr(binary *Binary) any
+	VisitInterpolationExpr(interpolation *Interpolation) any
+	VisitGroupingExpr(grouping *Grouping) any
+	VisitUnaryExpr(unary *Unary) any
+	VisitLogicalExpr(logical *Logical) any
+	VisitVariableExpr(variable *Variable) any
+	VisitAssignExpr(assign *Assign) any
+	VisitCallExpr(call *Call) any
+	VisitConditionalExpr(conditional *Conditional) any
+	VisitGetExpr(get *Get) any
+	VisitSetExpr(set *Set) any
+	VisitThisExpr(this *This) any
+	VisitSuperExpr(super *Super) any
+	VisitFunctionExpr(function *FunctionExpr) any
+	VisitArrayLiteralExpr(array *ArrayLiteral) any
+	VisitIndexExpr(index *Index) any
+	VisitIndexAssignExpr(assign *IndexAssign) any
+	VisitMapLiteralExpr(m *MapLiteral) any
+}
+
+// Literal expression. Token is the number or string token it was parsed
+// from, so tools can print it as written, and the zero Token otherwise.
+type Literal struct {
+	Value LoxLiteral
+	Token Token
+}
+
+func (l *Literal) Accept(visitor Visitor) any {
+	return visitor.VisitLiteralExpr(l)
+}
+
+// Binary expression
+type Binary struct {
+	Left  Expr
+	Right Expr
+	Op    Token
+}
+
+func (b *Binary) Accept(visitor Visitor) any {
+	return visitor.VisitBinaryExpr(b)
+}
+
+// Interpolation expression, e.g. "Hello, ${name}!"
+type Interpolation struct {
+	Parts []Expr
+}
+
+func (i *Interpolation) Accept(visitor Visitor) any {
+	return visitor.VisitInterpolationExpr(i)
+}
+
+// Grouping expression, e.g. (1 + 2)
+type Grouping struct {
+	Expression Expr
+}
+
+func (g *Grouping) Accept(visitor Visitor) any {
+	return visitor.VisitGroupingExpr(g)
+}
+
+// Unary expression, e.g. -a or !a
+type Unary struct {
+	Op    Token
+	Right Expr
+}
+
+func (u *Unary) Accept(visitor Visitor) any {
+	return visitor.VisitUnaryExpr(u)
+}
+
+// Logical expression, the short-circuiting "and" and "or"
+type Logical struct {
+	Left  Expr
+	Right Expr
+	Op    Token
+}
+
+func (l *Logical) Accept(visitor Visitor) any {
+	return visitor.VisitLogicalExpr(l)
+}
+
+// Variable expression, a read of a named variable
+type Variable struct {
+	Name Token
+}
+
+func (v *Variable) Accept(visitor Visitor) any {
+	return visitor.VisitVariableExpr(v)
+}
+
+// Assign expression, e.g. a = 1
+type Assign struct {
+	Name  Token
+	Value Expr
+}
+
+func (a *Assign) Accept(visitor Visitor) any {
+	return visitor.VisitAssignExpr(a)
+}
+
+// Conditional expression, e.g. a ? b : c
+type Conditional struct {
+	Condition  Expr
+	ThenBranch Expr
+	ElseBranch Expr
+}
+
+func (c *Conditional) Accept(visitor Visitor) any {
+	return visitor.VisitConditionalExpr(c)
+}
+
+// Call expression, e.g. f(1, 2)
+type Call struct {
+	Callee    Expr
+	Paren     Token // The closing paren, used to report errors
+	Arguments []Expr
+}
+
+func (c *Call) Accept(visitor Visitor) any {
+	return visitor.VisitCallExpr(c)
+}
+
+// Get expression, a property access like a.b
+type Get struct {
+	Object Expr
+	Name   Token
+}
+
+func (g *Get) Accept(visitor Visitor) any {
+	return visitor.VisitGetExpr(g)
+}
+
+// Set expression, a property assignment like a.b = c
+type Set struct {
+	Object Expr
+	Name   Token
+	Value  Expr
+}
+
+func (s *Set) Accept(visitor Visitor) any {
+	return visitor.VisitSetExpr(s)
+}
+
+// This expression, the instance a method was accessed on
+type This struct {
+	Keyword Token
+}
+
+func (t *This) Accept(visitor Visitor) any {
+	return visitor.VisitThisExpr(t)
+}
+
+// Super expression, a superclass method access like super.method
+type Super struct {
+	Keyword Token
+	Method  Token
+}
+
+func (s *Super) Accept(visitor Visitor) any {
+	return visitor.VisitSuperExpr(s)
+}
+
+// FunctionExpr is an anonymous function, e.g. fun (a) { return a; }. Its
+// Declaration has no name.
+type FunctionExpr struct {
+	Keyword     Token
+	Declaration *Function
+}
+
+func (f *FunctionExpr) Accept(visitor Visitor) any {
+	return visitor.VisitFunctionExpr(f)
+}
+
+// ArrayLiteral expression, e.g. [1, 2, 3]
+type ArrayLiteral struct {
+	Bracket  Token // The opening bracket
+	Elements []Expr
+}
+
+func (a *ArrayLiteral) Accept(visitor Visitor) any {
+	return visitor.VisitArrayLiteralExpr(a)
+}
+
+// Index expression, e.g. a[i]
+type Index struct {
+	Object  Expr
+	Bracket Token // The closing bracket, used to report errors
+	Index   Expr
+}
+
+func (i *Index) Accept(visitor Visitor) any {
+	return visitor.VisitIndexExpr(i)
+}
+
+// IndexAssign expression, e.g. a[i] = value
+type IndexAssign struct {
+	Object  Expr
+	Bracket Token
+	Index   Expr
+	Value   Expr
+}
+
+func (i *IndexAssign) Accept(visitor Visitor) any {
+	return visitor.VisitIndexAssignExpr(i)
+}
+
+// MapLiteral expression, e.g. {"a": 1, "b": 2}. Keys[i] maps to Values[i].
+type MapLiteral struct {
+	Brace  Token // The opening brace, used to report errors
+	Keys   []Expr
+	Values []Expr
+}
+
+func (m *MapLiteral) Accept(visitor Visitor) any {
+	return visitor.VisitMapLiteralExpr(m)
+}
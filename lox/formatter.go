@@ -0,0 +1,508 @@
+package lox
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FormatAST prints a parsed program back as canonically formatted Lox:
+// one statement per line, two-space indentation, single spaces around
+// binary operators and braces on the line that opens them. Formatting its
+// own output gives the same text again. Comments are not part of the tree,
+// so they are dropped; Format keeps them.
+func FormatAST(statements []Stmt) string {
+	return (&formatter{}).program(statements)
+}
+
+// Format formats source like FormatAST, keeping its comments. A comment
+// on a line of its own stays before the statement that follows it, and
+// one after a statement, or inside it, goes at the end of its line. If
+// source has syntax errors, they are returned instead.
+func Format(source string) (string, []ScanError, []ParseError) {
+	return formatSource(source, DefaultMaxErrors)
+}
+
+// formatSource is Format, giving up after maxErrors syntax errors
+func formatSource(source string, maxErrors int) (string, []ScanError, []ParseError) {
+	tokens, scanErrors := tokenize(source, true, maxErrors)
+	var code, comments []Token
+	for _, tok := range tokens {
+		if tok._type == COMMENT {
+			comments = append(comments, tok)
+		} else {
+			code = append(code, tok)
+		}
+	}
+	parser := NewParser(code)
+	parser.MaxErrors = maxErrors
+	parser.spans = make(map[Stmt]span)
+	statements, parseErrors := parser.Parse()
+	if len(scanErrors)+len(parseErrors) > 0 {
+		return "", scanErrors, parseErrors
+	}
+
+	f := &formatter{comments: comments, code: code, spans: parser.spans}
+	return f.program(statements), nil, nil
+}
+
+// isDeclaration reports whether stmt declares a function or class, which
+// are set apart from their neighbours by a blank line at the top level
+func isDeclaration(stmt Stmt) bool {
+	switch stmt.(type) {
+	case *Function, *Class:
+		return true
+	}
+	return false
+}
+
+// formatter renders each node as source text. Nested lines are indented to
+// the current depth, the first line is left for the caller to place.
+type formatter struct {
+	indent int
+
+	// When formatting with Format, the comments not printed yet and the
+	// tokens and statement spans to place them by
+	comments []Token
+	code     []Token
+	spans    map[Stmt]span
+}
+
+func (f *formatter) program(statements []Stmt) string {
+	var builder strings.Builder
+	for i, stmt := range statements {
+		if i > 0 && (isDeclaration(stmt) || isDeclaration(statements[i-1])) {
+			builder.WriteByte('\n')
+		}
+		builder.WriteString(f.line(stmt, func() string { return f.stmt(stmt) }))
+	}
+	builder.WriteString(f.commentLines(math.MaxInt))
+	return builder.String()
+}
+
+// line renders a statement or method on a line of its own, after the
+// comments that come before it and followed by the rest of the comments up
+// to the end of its last line
+func (f *formatter) line(stmt Stmt, render func() string) string {
+	s, ok := f.spans[stmt]
+	if !ok {
+		return f.indentation() + render() + "\n"
+	}
+	leading := f.commentLines(s.start)
+	text := f.indentation() + render()
+	for len(f.comments) > 0 {
+		comment := f.comments[0]
+		if comment.startOffset >= s.end && (comment.line != s.endLine || comment.startOffset > f.nextCode(s.end)) {
+			break
+		}
+		text += " " + comment.lexeme
+		f.comments = f.comments[1:]
+	}
+	return leading + text + "\n"
+}
+
+// closingComments renders the comments between the end of the last of
+// stmts and the code after it, which close the block stmts are in
+func (f *formatter) closingComments(stmts []Stmt) string {
+	end := -1
+	for _, stmt := range stmts {
+		if s, ok := f.spans[stmt]; ok {
+			end = max(end, s.end)
+		}
+	}
+	if end < 0 {
+		return ""
+	}
+	return f.commentLines(f.nextCode(end))
+}
+
+// commentLines renders each comment before offset on a line of its own
+func (f *formatter) commentLines(offset int) string {
+	var builder strings.Builder
+	for len(f.comments) > 0 && f.comments[0].startOffset < offset {
+		builder.WriteString(f.indentation() + f.comments[0].lexeme + "\n")
+		f.comments = f.comments[1:]
+	}
+	return builder.String()
+}
+
+// nextCode returns the offset of the first token that isn't a comment at
+// or after offset
+func (f *formatter) nextCode(offset int) int {
+	i := sort.Search(len(f.code), func(i int) bool { return f.code[i].startOffset >= offset })
+	if i == len(f.code) {
+		return math.MaxInt
+	}
+	return f.code[i].startOffset
+}
+
+func (f *formatter) expr(expr Expr) string {
+	return expr.Accept(f).(string)
+}
+
+func (f *formatter) stmt(stmt Stmt) string {
+	return stmt.Accept(f).(string)
+}
+
+func (f *formatter) exprs(exprs []Expr) string {
+	parts := make([]string, len(exprs))
+	for i, expr := range exprs {
+		parts[i] = f.expr(expr)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f *formatter) indentation() string {
+	return strings.Repeat("  ", f.indent)
+}
+
+// block renders statements between braces, one per line
+func (f *formatter) block(stmts []Stmt) string {
+	if len(stmts) == 0 {
+		return "{}"
+	}
+	var builder strings.Builder
+	builder.WriteString("{\n")
+	f.indent++
+	for _, stmt := range stmts {
+		builder.WriteString(f.line(stmt, func() string { return f.stmt(stmt) }))
+	}
+	builder.WriteString(f.closingComments(stmts))
+	f.indent--
+	builder.WriteString(f.indentation() + "}")
+	return builder.String()
+}
+
+// body renders the body of a control flow statement, on the same line if it
+// is a block and indented on the next one otherwise
+func (f *formatter) body(stmt Stmt) string {
+	if block, ok := stmt.(*Block); ok {
+		return " " + f.block(block.Statements)
+	}
+	f.indent++
+	defer func() { f.indent-- }()
+	return "\n" + f.indentation() + f.stmt(stmt)
+}
+
+func (f *formatter) function(function *Function) string {
+	if function.IsGetter {
+		return function.Name.lexeme + " " + f.block(function.Body)
+	}
+	params := make([]string, len(function.Params))
+	for i, param := range function.Params {
+		params[i] = param.lexeme
+	}
+	return function.Name.lexeme + "(" + strings.Join(params, ", ") + ") " + f.block(function.Body)
+}
+
+// update renders an assignment the parser desugared from target op= value
+// or a prefix ++ or --, which reuse the target as the left operand. It
+// returns false for any other assignment.
+func (f *formatter) update(target Expr, value Expr) (string, bool) {
+	binary, ok := value.(*Binary)
+	if !ok || !sameTarget(binary.Left, target) {
+		return "", false
+	}
+	if binary.Op._type == PLUS_PLUS || binary.Op._type == MINUS_MINUS {
+		return binary.Op.lexeme + f.expr(binary.Left), true
+	}
+	return f.expr(binary.Left) + " " + binary.Op.lexeme + "= " + f.expr(binary.Right), true
+}
+
+// sameTarget reports whether left is the very expression the parser reused
+// from an assignment target, rather than one that only looks the same
+func sameTarget(left Expr, target Expr) bool {
+	switch left := left.(type) {
+	case *Variable:
+		name, ok := target.(*Variable)
+		return ok && left.Name == name.Name
+	case *Get:
+		get, ok := target.(*Get)
+		return ok && left.Object == get.Object && left.Name == get.Name
+	case *Index:
+		index, ok := target.(*Index)
+		return ok && left.Object == index.Object && left.Index == index.Index
+	}
+	return false
+}
+
+// VisitLiteralExpr prints numbers and strings as they were written, so hex
+// and exponent forms and escapes survive, unless the parser made them up
+func (f *formatter) VisitLiteralExpr(literal *Literal) any {
+	if literal.Token.lexeme != "" {
+		return literal.Token.lexeme
+	}
+	switch value := literal.Value.(type) {
+	case LoxString:
+		return `"` + strings.ReplaceAll(value.value, "${", `\${`) + `"`
+	case LoxNumber:
+		return strconv.FormatFloat(value.value, 'f', -1, 64)
+	}
+	return stringify(literal.Value)
+}
+
+func (f *formatter) VisitBinaryExpr(binary *Binary) any {
+	if binary.Op._type == COMMA {
+		return f.expr(binary.Left) + ", " + f.expr(binary.Right)
+	}
+	return f.expr(binary.Left) + " " + binary.Op.lexeme + " " + f.expr(binary.Right)
+}
+
+// VisitInterpolationExpr alternates text segments with the embedded
+// expressions, starting and ending with text
+func (f *formatter) VisitInterpolationExpr(interpolation *Interpolation) any {
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for i, part := range interpolation.Parts {
+		if i%2 == 1 {
+			builder.WriteString("${" + f.expr(part) + "}")
+			continue
+		}
+		text := f.expr(part)
+		builder.WriteString(text[1 : len(text)-1])
+	}
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+func (f *formatter) VisitGroupingExpr(grouping *Grouping) any {
+	return "(" + f.expr(grouping.Expression) + ")"
+}
+
+func (f *formatter) VisitUnaryExpr(unary *Unary) any {
+	right := f.expr(unary.Right)
+	// Keep - -x from scanning as a decrement
+	if strings.HasPrefix(right, unary.Op.lexeme) && unary.Op._type == MINUS {
+		return unary.Op.lexeme + " " + right
+	}
+	return unary.Op.lexeme + right
+}
+
+func (f *formatter) VisitLogicalExpr(logical *Logical) any {
+	return f.expr(logical.Left) + " " + logical.Op.lexeme + " " + f.expr(logical.Right)
+}
+
+func (f *formatter) VisitVariableExpr(variable *Variable) any {
+	return variable.Name.lexeme
+}
+
+func (f *formatter) VisitAssignExpr(assign *Assign) any {
+	if text, ok := f.update(&Variable{Name: assign.Name}, assign.Value); ok {
+		return text
+	}
+	return assign.Name.lexeme + " = " + f.expr(assign.Value)
+}
+
+func (f *formatter) VisitCallExpr(call *Call) any {
+	return f.expr(call.Callee) + "(" + f.exprs(call.Arguments) + ")"
+}
+
+func (f *formatter) VisitConditionalExpr(conditional *Conditional) any {
+	return f.expr(conditional.Condition) + " ? " + f.expr(conditional.ThenBranch) + " : " + f.expr(conditional.ElseBranch)
+}
+
+func (f *formatter) VisitGetExpr(get *Get) any {
+	return f.expr(get.Object) + "." + get.Name.lexeme
+}
+
+func (f *formatter) VisitSetExpr(set *Set) any {
+	if text, ok := f.update(&Get{Object: set.Object, Name: set.Name}, set.Value); ok {
+		return text
+	}
+	return f.expr(set.Object) + "." + set.Name.lexeme + " = " + f.expr(set.Value)
+}
+
+func (f *formatter) VisitThisExpr(this *This) any {
+	return "this"
+}
+
+func (f *formatter) VisitSuperExpr(super *Super) any {
+	return "super." + super.Method.lexeme
+}
+
+func (f *formatter) VisitFunctionExpr(function *FunctionExpr) any {
+	return "fun " + f.function(function.Declaration)
+}
+
+func (f *formatter) VisitArrayLiteralExpr(array *ArrayLiteral) any {
+	return "[" + f.exprs(array.Elements) + "]"
+}
+
+func (f *formatter) VisitIndexExpr(index *Index) any {
+	return f.expr(index.Object) + "[" + f.expr(index.Index) + "]"
+}
+
+func (f *formatter) VisitIndexAssignExpr(assign *IndexAssign) any {
+	target := &Index{Object: assign.Object, Bracket: assign.Bracket, Index: assign.Index}
+	if text, ok := f.update(target, assign.Value); ok {
+		return text
+	}
+	return f.expr(target) + " = " + f.expr(assign.Value)
+}
+
+func (f *formatter) VisitMapLiteralExpr(literal *MapLiteral) any {
+	entries := make([]string, len(literal.Keys))
+	for i, key := range literal.Keys {
+		entries[i] = f.expr(key) + ": " + f.expr(literal.Values[i])
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+func (f *formatter) VisitExpressionStmt(stmt *Expression) any {
+	return f.expr(stmt.Expression) + ";"
+}
+
+func (f *formatter) VisitPrintStmt(stmt *Print) any {
+	return "print " + f.expr(stmt.Expression) + ";"
+}
+
+func (f *formatter) VisitVarStmt(stmt *Var) any {
+	if stmt.Const {
+		return "const " + stmt.Name.lexeme + " = " + f.expr(stmt.Initializer) + ";"
+	}
+	if stmt.Initializer == nil {
+		return "var " + stmt.Name.lexeme + ";"
+	}
+	return "var " + stmt.Name.lexeme + " = " + f.expr(stmt.Initializer) + ";"
+}
+
+// VisitBlockStmt turns a for loop with an initializer, which the parser
+// wraps in a block, back into a for statement
+func (f *formatter) VisitBlockStmt(stmt *Block) any {
+	if len(stmt.Statements) == 2 {
+		if loop, ok := stmt.Statements[1].(*While); ok && loop.Increment != nil {
+			initializer := f.stmt(stmt.Statements[0])
+			return f.forLoop(initializer, loop)
+		}
+	}
+	return f.block(stmt.Statements)
+}
+
+func (f *formatter) forLoop(initializer string, loop *While) string {
+	return "for (" + initializer + " " + f.expr(loop.Condition) + "; " + f.expr(loop.Increment) + ")" + f.body(loop.Body)
+}
+
+func (f *formatter) VisitIfStmt(stmt *If) any {
+	text := "if (" + f.expr(stmt.Condition) + ")" + f.body(stmt.ThenBranch)
+	if stmt.ElseBranch == nil {
+		return text
+	}
+	if _, ok := stmt.ThenBranch.(*Block); ok {
+		text += " "
+	} else {
+		text += "\n" + f.indentation()
+	}
+	if _, ok := stmt.ElseBranch.(*If); ok {
+		return text + "else " + f.stmt(stmt.ElseBranch)
+	}
+	return text + "else" + f.body(stmt.ElseBranch)
+}
+
+// VisitWhileStmt also prints for loops without an initializer, the only
+// loops that have an increment
+func (f *formatter) VisitWhileStmt(stmt *While) any {
+	if stmt.DoWhile {
+		text := "do" + f.body(stmt.Body)
+		if _, ok := stmt.Body.(*Block); ok {
+			text += " "
+		} else {
+			text += "\n" + f.indentation()
+		}
+		return text + "while (" + f.expr(stmt.Condition) + ");"
+	}
+	if stmt.Increment != nil {
+		return f.forLoop(";", stmt)
+	}
+	return "while (" + f.expr(stmt.Condition) + ")" + f.body(stmt.Body)
+}
+
+func (f *formatter) VisitFunctionStmt(stmt *Function) any {
+	return "fun " + f.function(stmt)
+}
+
+func (f *formatter) VisitReturnStmt(stmt *Return) any {
+	if stmt.Value == nil {
+		return "return;"
+	}
+	return "return " + f.expr(stmt.Value) + ";"
+}
+
+func (f *formatter) VisitClassStmt(stmt *Class) any {
+	text := "class " + stmt.Name.lexeme
+	if stmt.Superclass != nil {
+		text += " < " + stmt.Superclass.Name.lexeme
+	}
+	if len(stmt.StaticMethods)+len(stmt.Methods) == 0 {
+		return text + " {}"
+	}
+	var builder strings.Builder
+	builder.WriteString(text + " {\n")
+	f.indent++
+	var methods []Stmt
+	for _, method := range stmt.StaticMethods {
+		builder.WriteString(f.line(method, func() string { return "class " + f.function(method) }))
+		methods = append(methods, method)
+	}
+	for _, method := range stmt.Methods {
+		builder.WriteString(f.line(method, func() string { return f.function(method) }))
+		methods = append(methods, method)
+	}
+	builder.WriteString(f.closingComments(methods))
+	f.indent--
+	builder.WriteString(f.indentation() + "}")
+	return builder.String()
+}
+
+func (f *formatter) VisitBreakStmt(stmt *Break) any {
+	return "break;"
+}
+
+func (f *formatter) VisitContinueStmt(stmt *Continue) any {
+	return "continue;"
+}
+
+// VisitSwitchStmt indents case labels inside the braces and each arm's
+// statements one level further
+func (f *formatter) VisitSwitchStmt(stmt *Switch) any {
+	var builder strings.Builder
+	builder.WriteString("switch (" + f.expr(stmt.Subject) + ") {\n")
+	f.indent++
+	arm := func(label string, body []Stmt) {
+		builder.WriteString(f.indentation() + label + ":\n")
+		f.indent++
+		for _, inner := range body {
+			builder.WriteString(f.line(inner, func() string { return f.stmt(inner) }))
+		}
+		builder.WriteString(f.closingComments(body))
+		f.indent--
+	}
+	for _, c := range stmt.Cases {
+		arm("case "+f.expr(c.Value), c.Body)
+	}
+	if stmt.Default != nil {
+		arm("default", stmt.Default)
+	}
+	f.indent--
+	builder.WriteString(f.indentation() + "}")
+	return builder.String()
+}
+
+func (f *formatter) VisitThrowStmt(stmt *Throw) any {
+	return "throw " + f.expr(stmt.Value) + ";"
+}
+
+func (f *formatter) VisitTryStmt(stmt *Try) any {
+	text := "try " + f.block(stmt.Body)
+	if stmt.Catch != nil {
+		text += " catch (" + stmt.CatchName.lexeme + ") " + f.block(stmt.Catch)
+	}
+	if stmt.Finally != nil {
+		text += " finally " + f.block(stmt.Finally)
+	}
+	return text
+}
+
+func (f *formatter) VisitForEachStmt(stmt *ForEach) any {
+	return "for (var " + stmt.Name.lexeme + " in " + f.expr(stmt.Collection) + ")" + f.body(stmt.Body)
+}
@@ -0,0 +1,91 @@
+package lox
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFormat formats each testdata/fmt/*.lox program and compares it with
+// the .golden file beside it. Run with -update to rewrite the golden files.
+func TestFormat(t *testing.T) {
+	programs, err := filepath.Glob(filepath.Join("testdata", "fmt", "*.lox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, program := range programs {
+		name := strings.TrimSuffix(filepath.Base(program), ".lox")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := format(t, string(source))
+
+			goldenPath := strings.TrimSuffix(program, ".lox") + ".golden"
+			if *update {
+				if err := os.WriteFile(goldenPath, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			} else if want, err := os.ReadFile(goldenPath); err != nil {
+				t.Fatal(err)
+			} else if got != string(want) {
+				t.Errorf("Format(%s) =\n%s\nwant:\n%s", program, got, want)
+			}
+
+			if again := format(t, got); again != got {
+				t.Errorf("formatting the output again gave:\n%s\nwant:\n%s", again, got)
+			}
+		})
+	}
+}
+
+func format(t *testing.T, source string) string {
+	t.Helper()
+	formatted, scanErrors, parseErrors := Format(source)
+	if len(scanErrors)+len(parseErrors) > 0 {
+		t.Fatalf("Format errors: %v %v", scanErrors, parseErrors)
+	}
+	return formatted
+}
+
+func TestFormatLiterals(t *testing.T) {
+	for _, source := range []string{
+		"print 0x1F;\n",
+		"print 1.5e3;\n",
+		"print 1.50;\n",
+		"print \"a \\${b}\";\n",
+		"print \"${1 + 2} and \\${x}\";\n",
+	} {
+		if got := format(t, source); got != source {
+			t.Errorf("Format(%q) = %q", source, got)
+		}
+	}
+	// Literals the parser or the folder made up have no lexeme
+	expectFormatted(t, "++x;", "++x;\n")
+	statements, _ := parse(t, "print 0x10 + 1;")
+	Fold(statements)
+	if got, want := FormatAST(statements), "print 17;\n"; got != want {
+		t.Errorf("FormatAST(folded) = %q, want %q", got, want)
+	}
+}
+
+func TestFormatBlockComments(t *testing.T) {
+	expectFormatted(t, "var a=1; /* trailing */\n/* own\n   line */\nprint a;",
+		"var a = 1; /* trailing */\n/* own\n   line */\nprint a;\n")
+	expectFormatted(t, "{ /* inside */ print 1; }", "{\n  /* inside */\n  print 1;\n}\n")
+}
+
+func TestFormatErrors(t *testing.T) {
+	if _, _, parseErrors := Format("print ;"); len(parseErrors) != 1 {
+		t.Errorf("Format(print ;) parse errors = %v, want one", parseErrors)
+	}
+}
+
+func expectFormatted(t *testing.T, source, want string) {
+	t.Helper()
+	if got := format(t, source); got != want {
+		t.Errorf("Format(%q) = %q, want %q", source, got, want)
+	}
+}
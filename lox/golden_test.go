@@ -0,0 +1,97 @@
+package lox
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden is the expected result of running a program in testdata
+type golden struct {
+	stdout, stderr string
+	exit           int
+}
+
+// Golden files hold a section for each part of the result, in this order:
+//
+//	-- stdout --
+//	...
+//	-- stderr --
+//	...
+//	-- exit --
+//	70
+const (
+	stdoutHeader = "-- stdout --\n"
+	stderrHeader = "-- stderr --\n"
+	exitHeader   = "-- exit --\n"
+)
+
+func (g golden) String() string {
+	return stdoutHeader + g.stdout + stderrHeader + g.stderr + exitHeader + strconv.Itoa(g.exit) + "\n"
+}
+
+func parseGolden(text string) (golden, error) {
+	rest, ok := strings.CutPrefix(text, stdoutHeader)
+	if !ok {
+		return golden{}, fmt.Errorf("missing %q", stdoutHeader)
+	}
+	stdout, rest, ok := strings.Cut(rest, stderrHeader)
+	if !ok {
+		return golden{}, fmt.Errorf("missing %q", stderrHeader)
+	}
+	stderr, rest, ok := strings.Cut(rest, exitHeader)
+	if !ok {
+		return golden{}, fmt.Errorf("missing %q", exitHeader)
+	}
+	exit, err := strconv.Atoi(strings.TrimSpace(rest))
+	if err != nil {
+		return golden{}, err
+	}
+	return golden{stdout: stdout, stderr: stderr, exit: exit}, nil
+}
+
+// TestGolden runs each testdata/*.lox program and compares its output,
+// errors and exit code with the .golden file beside it. Run with -update to
+// rewrite the golden files from the current results.
+func TestGolden(t *testing.T) {
+	programs, err := filepath.Glob(filepath.Join("testdata", "*.lox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, program := range programs {
+		name := strings.TrimSuffix(filepath.Base(program), ".lox")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got golden
+			got.stdout, got.stderr, got.exit = run(t, string(source))
+
+			goldenPath := strings.TrimSuffix(program, ".lox") + ".golden"
+			if *update {
+				if err := os.WriteFile(goldenPath, []byte(got.String()), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			text, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := parseGolden(string(text))
+			if err != nil {
+				t.Fatalf("%s: %v", goldenPath, err)
+			}
+			if got != want {
+				t.Errorf("%s gave:\n%s\nwant:\n%s", program, got, want)
+			}
+		})
+	}
+}
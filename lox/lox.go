@@ -0,0 +1,279 @@
+// Package lox implements a tree-walk interpreter for the Lox language from
+// Crafting Interpreters.
+package lox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Interpreter runs Lox programs from source. It scans, parses and resolves
+// them, reporting errors to Err, then runs them with its Evaluator. Each
+// Interpreter has its own globals, streams and settings, so several can
+// run side by side.
+type Interpreter struct {
+	*Evaluator
+
+	// WarnUnused enables warnings for local variables that are never read
+	WarnUnused bool
+
+	// FoldConstants makes Run, RunLine and RunExpression fold constant
+	// subexpressions before resolving
+	FoldConstants bool
+
+	// MaxErrors is the error limit of the scanners and parsers. Past it they
+	// stop and add a final "Too many errors." rather than report every error
+	// in a badly broken file. 0 means no limit.
+	MaxErrors int
+
+	hadError bool // Set once a scan, parse or resolution error is reported
+}
+
+// NewInterpreter returns an Interpreter with a new Evaluator, using the
+// standard streams
+func NewInterpreter() *Interpreter {
+	return &Interpreter{
+		Evaluator: NewEvaluator(),
+		MaxErrors: DefaultMaxErrors,
+	}
+}
+
+// DefaultMaxErrors is the error limit of new scanners, parsers and
+// Interpreters
+const DefaultMaxErrors = 100
+
+// tooManyErrors reports whether count errors reach limit, 0 meaning none do
+func tooManyErrors(count int, limit int) bool {
+	return limit > 0 && count >= limit
+}
+
+// Exit codes returned by Run, following the sysexits convention jlox uses
+const (
+	ExitOK           = 0
+	ExitSyntaxError  = 65 // A scan, parse or resolution error
+	ExitRuntimeError = 70
+)
+
+// HadError reports whether a scan, parse or resolution error was reported
+func (in *Interpreter) HadError() bool {
+	return in.hadError
+}
+
+// ReportScanErrors reports each of errors to Err, setting HadError
+func (in *Interpreter) ReportScanErrors(errors []ScanError) {
+	for _, err := range errors {
+		in.report(err.Line, "", err.Message)
+	}
+}
+
+// ReportParseErrors reports each of errors to Err, setting HadError
+func (in *Interpreter) ReportParseErrors(errors []ParseError) {
+	for _, err := range errors {
+		in.report(err.Token.line, where(err.Token), err.Message)
+	}
+}
+
+// report writes an error report to Err and records that there was one
+func (in *Interpreter) report(line int, where string, message string) {
+	report(in.Err, line, where, message)
+	in.hadError = true
+}
+
+// Tokenize is the package's Tokenize, with the interpreter's error limit
+func (in *Interpreter) Tokenize(source string) ([]Token, []ScanError) {
+	return tokenize(source, false, in.MaxErrors)
+}
+
+// TokenizeWithComments is Tokenize, keeping comments as COMMENT tokens
+func (in *Interpreter) TokenizeWithComments(source string) ([]Token, []ScanError) {
+	return tokenize(source, true, in.MaxErrors)
+}
+
+// NewParser is the package's NewParser, with the interpreter's error limit
+func (in *Interpreter) NewParser(tokens []Token) Parser {
+	parser := NewParser(tokens)
+	parser.MaxErrors = in.MaxErrors
+	return parser
+}
+
+// Format is the package's Format, with the interpreter's error limit
+func (in *Interpreter) Format(source string) (string, []ScanError, []ParseError) {
+	return formatSource(source, in.MaxErrors)
+}
+
+// Run scans, parses, resolves and interprets source, and returns the exit
+// code for the outcome
+func (in *Interpreter) Run(source string) int {
+	return in.RunContext(context.Background(), source)
+}
+
+// RunContext is Run, stopping the program when ctx is done
+func (in *Interpreter) RunContext(ctx context.Context, source string) int {
+	tokens, scanErrors := in.Tokenize(source)
+	parser := in.NewParser(tokens)
+	statements, parseErrors := parser.Parse()
+
+	// Stop if there was a syntax error
+	if reportSyntaxErrors(in.Err, source, scanErrors, parseErrors) {
+		in.hadError = true
+		return ExitSyntaxError
+	}
+
+	if in.FoldConstants {
+		Fold(statements)
+	}
+
+	if !in.resolve(statements) {
+		return ExitSyntaxError
+	}
+	return in.runtimeExit(in.InterpretContext(ctx, statements))
+}
+
+// resolve resolves statements for the Evaluator, and reports whether that
+// succeeded
+func (in *Interpreter) resolve(statements []Stmt) bool {
+	resolver := NewResolver(in.Evaluator)
+	resolver.WarnUnused = in.WarnUnused
+	resolver.Resolve(statements)
+	if resolver.hadError {
+		in.hadError = true
+	}
+	return !resolver.hadError
+}
+
+// reportSyntaxErrors writes scan and parse errors in source to w, and
+// reports whether there were any
+func reportSyntaxErrors(w io.Writer, source string, scanErrors []ScanError, parseErrors []ParseError) bool {
+	for _, err := range scanErrors {
+		reportAt(w, source, err.Offset, err.Line, "", err.Message)
+	}
+	for _, err := range parseErrors {
+		reportAt(w, source, err.Token.startOffset, err.Token.line, where(err.Token), err.Message)
+	}
+	return len(scanErrors)+len(parseErrors) > 0
+}
+
+// runtimeExit turns the error from running a program into an exit code,
+// reporting runtime errors. A call to exit() ends the program with its code.
+func (in *Interpreter) runtimeExit(err error) int {
+	if exit, ok := err.(exitRequest); ok {
+		return exit.code
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintln(in.Err, "Execution timed out.")
+		return ExitRuntimeError
+	}
+	if err != nil {
+		fmt.Fprintln(in.Err, err)
+		return ExitRuntimeError
+	}
+	return ExitOK
+}
+
+// RunLine runs one line typed at the REPL. A line holding a single
+// expression without a trailing semicolon is evaluated and its value
+// printed, anything else runs like a file. Errors are reported but never
+// carry over to the next line, HadError only covers the last one. exited
+// reports whether the line called exit(), in which case the session should
+// end with code.
+func (in *Interpreter) RunLine(line string) (code int, exited bool) {
+	in.exited = false
+	in.hadError = false
+
+	tokens, errors := in.Tokenize(line)
+	if len(errors) == 0 {
+		parser := in.NewParser(tokens)
+		if expr, parseErrors := parser.ParseExpression(); len(parseErrors) == 0 {
+			code = in.printExpression(expr)
+			return code, in.exited
+		}
+	}
+	code = in.Run(line)
+	return code, in.exited
+}
+
+// RunExpression evaluates source, which must be a single expression, and
+// prints its value. It returns the exit code like Run.
+func (in *Interpreter) RunExpression(source string) int {
+	tokens, scanErrors := in.Tokenize(source)
+	parser := in.NewParser(tokens)
+	expr, parseErrors := parser.ParseExpression()
+	if reportSyntaxErrors(in.Err, source, scanErrors, parseErrors) {
+		in.hadError = true
+		return ExitSyntaxError
+	}
+	return in.printExpression(expr)
+}
+
+// printExpression folds, resolves and evaluates expr, then prints its value
+func (in *Interpreter) printExpression(expr Expr) int {
+	// Folding rewrites the statement's expression in place
+	statement := &Expression{Expression: expr}
+	if in.FoldConstants {
+		Fold([]Stmt{statement})
+	}
+	if !in.resolve([]Stmt{statement}) {
+		return ExitSyntaxError
+	}
+
+	value, err := in.Evaluate(statement.Expression)
+	if err != nil {
+		return in.runtimeExit(err)
+	}
+	fmt.Fprintln(in.Out, stringify(value))
+	return ExitOK
+}
+
+// report writes an error report to w
+func report(w io.Writer, line int, where string, message string) {
+	fmt.Fprintf(w, "[line %d] Error%s: %s\n", line, where, message)
+}
+
+// reportAt is report followed by the line of source holding offset, with a
+// caret under offset:
+//
+//	[line 2] Error: Unexpected character: @
+//	var a = @;
+//	        ^
+func reportAt(w io.Writer, source string, offset int, line int, where string, message string) {
+	report(w, line, where, message)
+	fmt.Fprint(w, caret(source, offset))
+}
+
+// caret returns the line of source holding offset and a line with a caret
+// under it. An offset at the end points just past the last character, not
+// at the empty line after a trailing newline.
+func caret(source string, offset int) string {
+	if offset >= len(source) {
+		offset = len(strings.TrimRight(source, " \t\r\n"))
+	}
+	start := strings.LastIndexByte(source[:offset], '\n') + 1
+	end := strings.IndexByte(source[offset:], '\n')
+	if end < 0 {
+		end = len(source)
+	} else {
+		end += offset
+	}
+
+	// Keep tabs so the caret lines up however they are displayed
+	var indent strings.Builder
+	for _, char := range source[start:offset] {
+		if char == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	return strings.TrimSuffix(source[start:end], "\r") + "\n" + indent.String() + "^\n"
+}
+
+// where describes the location of an error at tok
+func where(tok Token) string {
+	if tok._type == EOF {
+		return " at end"
+	}
+	return " at '" + tok.lexeme + "'"
+}
@@ -0,0 +1,267 @@
+package lox
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// run runs source with a new Interpreter and returns what the program
+// printed, what was reported on its error stream and the exit code
+func run(t *testing.T, source string) (stdout, stderr string, code int) {
+	t.Helper()
+	return runWith(t, NewInterpreter(), source)
+}
+
+// runWith is run with the given Interpreter
+func runWith(t *testing.T, interpreter *Interpreter, source string) (stdout, stderr string, code int) {
+	t.Helper()
+	var out, errs bytes.Buffer
+	interpreter.Out = &out
+	interpreter.Err = &errs
+	code = interpreter.Run(source)
+	return out.String(), errs.String(), code
+}
+
+// expectOutput runs source and fails unless it succeeds printing want
+func expectOutput(t *testing.T, source string, want string) {
+	t.Helper()
+	stdout, stderr, code := run(t, source)
+	if code != ExitOK || stderr != "" {
+		t.Fatalf("run(%q) exited %d, reporting:\n%s", source, code, stderr)
+	}
+	if stdout != want {
+		t.Errorf("run(%q) printed:\n%s\nwant:\n%s", source, stdout, want)
+	}
+}
+
+// expectError runs source and fails unless it exits with code, reporting
+// exactly want
+func expectError(t *testing.T, source string, code int, want string) {
+	t.Helper()
+	_, stderr, got := run(t, source)
+	if got != code {
+		t.Errorf("run(%q) exited %d, want %d; reported:\n%s", source, got, code, stderr)
+	}
+	if stderr != want {
+		t.Errorf("run(%q) reported:\n%s\nwant:\n%s", source, stderr, want)
+	}
+}
+
+func TestRunEndToEnd(t *testing.T) {
+	stdout, stderr, code := run(t, `
+		var greeting = "Hello";
+		fun greet(name) { return greeting + ", " + name + "!"; }
+		print greet("world");
+		print 1 + 2 * 3;`)
+	if code != ExitOK || stderr != "" {
+		t.Fatalf("Run exited %d, reporting:\n%s", code, stderr)
+	}
+	if want := "Hello, world!\n7\n"; stdout != want {
+		t.Errorf("Run printed %q, want %q", stdout, want)
+	}
+}
+
+func TestSeparateOutputs(t *testing.T) {
+	var outs, errs [2]bytes.Buffer
+	var codes [2]int
+	var wg sync.WaitGroup
+	for i := range outs {
+		interpreter := NewInterpreter()
+		interpreter.Out = &outs[i]
+		interpreter.Err = &errs[i]
+		source := fmt.Sprintf("for (var i = 0; i < 1000; i = i + 1) print %d;", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = interpreter.Run(source)
+		}()
+	}
+	wg.Wait()
+
+	for i := range outs {
+		if codes[i] != ExitOK || errs[i].Len() != 0 {
+			t.Fatalf("interpreter %d exited %d, reporting:\n%s", i, codes[i], errs[i].String())
+		}
+		if want := strings.Repeat(fmt.Sprintf("%d\n", i), 1000); outs[i].String() != want {
+			t.Errorf("interpreter %d printed output of the other", i)
+		}
+	}
+}
+
+func TestRunLine(t *testing.T) {
+	var out, errs bytes.Buffer
+	interpreter := NewInterpreter()
+	interpreter.Out = &out
+	interpreter.Err = &errs
+
+	for _, test := range []struct {
+		line         string
+		code         int
+		stdout, errs string
+	}{
+		{line: "1 + 2 * 3", stdout: "7\n"},
+		{line: "var x = 1;"},
+		{line: "x = x + 1", stdout: "2\n"},
+		{line: `"a" + "b";`},
+		{line: "print x;", stdout: "2\n"},
+		{line: "-nil", code: ExitRuntimeError, errs: "Operand must be a number.\n[line 1] in script\n"},
+		{line: "var = 1;", code: ExitSyntaxError, errs: "[line 1] Error at '=': Expect variable name.\nvar = 1;\n    ^\n"},
+		// Earlier errors don't carry over
+		{line: "x", stdout: "2\n"},
+	} {
+		out.Reset()
+		errs.Reset()
+		if code, exited := interpreter.RunLine(test.line); code != test.code || exited {
+			t.Errorf("RunLine(%q) = %d, %t, want %d, false", test.line, code, exited, test.code)
+		}
+		if out.String() != test.stdout || errs.String() != test.errs {
+			t.Errorf("RunLine(%q) printed %q, reporting %q; want %q, reporting %q",
+				test.line, out.String(), errs.String(), test.stdout, test.errs)
+		}
+	}
+}
+
+func TestRunLineExit(t *testing.T) {
+	interpreter := NewInterpreter()
+	if code, exited := interpreter.RunLine("exit(3);"); code != 3 || !exited {
+		t.Errorf("RunLine(exit(3);) = %d, %t, want 3, true", code, exited)
+	}
+}
+
+func TestErr(t *testing.T) {
+	expectError(t, "print 1;\n@", ExitSyntaxError, "[line 2] Error: Unexpected character: @\n@\n^\n")
+
+	var errs bytes.Buffer
+	interpreter := NewInterpreter()
+	interpreter.Err = &errs
+	_, scanErrors := interpreter.Tokenize("@")
+	interpreter.ReportScanErrors(scanErrors)
+	if want := "[line 1] Error: Unexpected character: @\n"; errs.String() != want {
+		t.Errorf("ReportScanErrors reported %q, want %q", errs.String(), want)
+	}
+	if !interpreter.HadError() {
+		t.Error("HadError() = false after ReportScanErrors")
+	}
+}
+
+func TestInterpreter(t *testing.T) {
+	expectOutput(t, `
+		var greeting = "hello";
+		fun greet(name) {
+			var message = greeting + ", " + name;
+			{
+				var greeting = "shadowed";
+				message = message + " (" + greeting + ")";
+			}
+			return message;
+		}
+		print greet("world");`, "hello, world (shadowed)\n")
+}
+
+func TestIndependentInterpreters(t *testing.T) {
+	first, second := NewInterpreter(), NewInterpreter()
+	first.WarnUnused = true
+	first.MaxErrors = 1
+	second.FoldConstants = true
+
+	// Globals belong to their interpreter
+	if stdout, stderr, _ := runWith(t, first, "var a = 1; print a;"); stdout != "1\n" || stderr != "" {
+		t.Fatalf("first printed %q, reporting %q", stdout, stderr)
+	}
+	if _, stderr, code := runWith(t, second, "print a;"); code != ExitRuntimeError || stderr != "Undefined variable 'a'.\n[line 1] in script\n" {
+		t.Errorf("second exited %d, reporting %q; want a's global undefined", code, stderr)
+	}
+
+	// As do settings and the error state
+	source := "{ var unused = 1; } @ @"
+	if _, stderr, _ := runWith(t, first, source); strings.Count(stderr, "Error") != 2 || !strings.Contains(stderr, "Too many errors.") {
+		t.Errorf("first with MaxErrors 1 reported:\n%s", stderr)
+	}
+	if _, stderr, _ := runWith(t, second, source); strings.Count(stderr, "Unexpected character") != 2 || strings.Contains(stderr, "Too many") {
+		t.Errorf("second reported:\n%s", stderr)
+	}
+	if !first.HadError() || !second.HadError() {
+		t.Fatal("HadError() = false after a syntax error")
+	}
+	third := NewInterpreter()
+	if third.HadError() {
+		t.Error("new interpreter HadError() = true")
+	}
+
+	if _, stderr, _ := runWith(t, first, "{ var unused = 1; }"); stderr != "[line 1] Warning: Local variable 'unused' is never used.\n" {
+		t.Errorf("first with WarnUnused reported %q", stderr)
+	}
+	if _, stderr, _ := runWith(t, second, "{ var unused = 1; }"); stderr != "" {
+		t.Errorf("second without WarnUnused reported %q", stderr)
+	}
+}
+
+func TestCaret(t *testing.T) {
+	expectError(t, "var a = 1;\nvar b = a @;\nprint b;\n", ExitSyntaxError,
+		"[line 2] Error: Unexpected character: @\n"+
+			"var b = a @;\n"+
+			"          ^\n")
+	// Tabs are kept so the caret lines up, and a multibyte character
+	// takes one column
+	expectError(t, "{\n\tvar é = 1~;\n}", ExitSyntaxError,
+		"[line 2] Error: Unexpected character: ~\n"+
+			"\tvar é = 1~;\n"+
+			"\t         ^\n")
+	// An error at the end points just past the last character
+	expectError(t, "print 1\n\n", ExitSyntaxError,
+		"[line 3] Error at end: Expect ';' after value.\n"+
+			"print 1\n"+
+			"       ^\n")
+	expectError(t, "print 1 +;\r\nprint 2;\r\n", ExitSyntaxError,
+		"[line 1] Error at ';': Expect expression.\n"+
+			"print 1 +;\n"+
+			"         ^\n")
+}
+
+func TestExitCodes(t *testing.T) {
+	for _, test := range []struct {
+		name, source string
+		code         int
+	}{
+		{"ok", "print 1;", ExitOK},
+		{"scan error", "@", ExitSyntaxError},
+		{"parse error", "print ;", ExitSyntaxError},
+		{"resolution error", "return 1;", ExitSyntaxError},
+		{"runtime error", "print -nil;", ExitRuntimeError},
+		{"exit", "exit(3);", 3},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, stderr, code := run(t, test.source); code != test.code {
+				t.Errorf("run(%q) exited %d, want %d; reported:\n%s", test.source, code, test.code, stderr)
+			}
+		})
+	}
+}
+
+func TestRunExpression(t *testing.T) {
+	for _, test := range []struct {
+		source, stdout, stderr string
+		code                   int
+	}{
+		{source: `"a" + "b"`, stdout: "ab\n"},
+		{source: "(1 + 2) * 3 == 9", stdout: "true\n"},
+		{source: "10 / 4", stdout: "2.5\n"},
+		{source: "(1 +", stderr: "[line 1] Error at end: Expect expression.\n(1 +\n    ^\n", code: ExitSyntaxError},
+		{source: `-"str"`, stderr: "Operand must be a number.\n[line 1] in script\n", code: ExitRuntimeError},
+	} {
+		var out, errs bytes.Buffer
+		interpreter := NewInterpreter()
+		interpreter.Out = &out
+		interpreter.Err = &errs
+		if code := interpreter.RunExpression(test.source); code != test.code {
+			t.Errorf("RunExpression(%q) = %d, want %d", test.source, code, test.code)
+		}
+		if out.String() != test.stdout || errs.String() != test.stderr {
+			t.Errorf("RunExpression(%q) printed %q, reporting %q; want %q, reporting %q",
+				test.source, out.String(), errs.String(), test.stdout, test.stderr)
+		}
+	}
+}
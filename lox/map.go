@@ -0,0 +1,90 @@
+package lox
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+)
+
+// LoxMap is the runtime representation of a map. Keys are strings or
+// numbers other than NaN, stored as their LoxString or LoxNumber value.
+type LoxMap struct {
+	entries map[any]any
+}
+
+// checkKey reports why value can't be used as a key, if it can't. NaN is
+// never equal to itself, so it could be stored but never looked up.
+func checkKey(value any) error {
+	switch value := value.(type) {
+	case LoxString:
+		return nil
+	case LoxNumber:
+		if math.IsNaN(value.value) {
+			return errors.New("Map keys can't be NaN.")
+		}
+		return nil
+	}
+	return errors.New("Map keys must be strings or numbers.")
+}
+
+// key checks that value can be used as a key, reporting errors at token
+func (m *LoxMap) key(token Token, value any) any {
+	if err := checkKey(value); err != nil {
+		panic(RuntimeError{Token: token, Message: err.Error()})
+	}
+	return value
+}
+
+// get returns the value for key, or nil if there is none
+func (m *LoxMap) get(token Token, key any) any {
+	if value, ok := m.entries[m.key(token, key)]; ok {
+		return value
+	}
+	return LoxNil{}
+}
+
+func (m *LoxMap) set(token Token, key any, value any) {
+	m.entries[m.key(token, key)] = value
+}
+
+// sortedKeys orders numbers before strings, each ascending, so maps always
+// print the same way
+func (m *LoxMap) sortedKeys() []any {
+	keys := make([]any, 0, len(m.entries))
+	for key := range m.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		switch a := keys[i].(type) {
+		case LoxNumber:
+			b, ok := keys[j].(LoxNumber)
+			return !ok || a.value < b.value
+		default:
+			b, ok := keys[j].(LoxString)
+			return ok && a.(LoxString).value < b.value
+		}
+	})
+	return keys
+}
+
+func (m *LoxMap) String() string {
+	return m.stringify(map[any]bool{})
+}
+
+// stringify prints the map as an element of the containers in printing,
+// like LoxArray.stringify
+func (m *LoxMap) stringify(printing map[any]bool) string {
+	if printing[m] {
+		return "{...}"
+	}
+	printing[m] = true
+	defer delete(printing, m)
+
+	keys := m.sortedKeys()
+	entries := make([]string, len(keys))
+	for i, key := range keys {
+		entries[i] = stringify(key) + ": " + stringifyElement(m.entries[key], printing)
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
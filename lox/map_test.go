@@ -0,0 +1,38 @@
+package lox
+
+import "testing"
+
+func TestMaps(t *testing.T) {
+	tests := []struct{ name, source, want string }{
+		{"literal", `print {"b": 2, "a": 1, 3: "x"};`, "{3: x, a: 1, b: 2}"},
+		{"empty", `print {};`, "{}"},
+		{"lookup", `var m = {"a": 1, 2: "two"}; print m["a"]; print m[2];`, "1\ntwo"},
+		{"missing key", `var m = {"a": 1}; print m["b"];`, "nil"},
+		{"assign", `var m = {"a": 1}; m["c"] = 3; m["a"] = 0; print m;`, "{a: 0, c: 3}"},
+		{"block", `{ var m = {"a": 1}; print m["a"]; }`, "1"},
+		{"contains itself", `var m = {"a": 1}; m["self"] = m; print m;`, "{a: 1, self: {...}}"},
+		{"in an array", `var m = {}; var a = [m]; m["a"] = a; print a; print m;`, "[{a: [...]}]\n{a: [{...}]}"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectOutput(t, test.source, test.want+"\n")
+		})
+	}
+}
+
+func TestMapKeyErrors(t *testing.T) {
+	for _, source := range []string{
+		`var m = {}; m[nil] = 1;`,
+		`print {[1]: 2};`,
+		`class A {} var m = {}; print m[A()];`,
+	} {
+		expectError(t, source, ExitRuntimeError, "Map keys must be strings or numbers.\n[line 1] in script\n")
+	}
+	for _, source := range []string{
+		`var m = {}; m[0/0] = 1;`,
+		`print {0/0: 1};`,
+		`var m = {}; print m[0/0];`,
+	} {
+		expectError(t, source, ExitRuntimeError, "Map keys can't be NaN.\n[line 1] in script\n")
+	}
+}
@@ -0,0 +1,425 @@
+package lox
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// NativeFunction is a function implemented in Go. An error returned by it
+// becomes a RuntimeError at the call site.
+type NativeFunction struct {
+	name     string
+	arity    int
+	maxArity int // Most arguments taken, if it takes more than arity
+	function func(evaluator *Evaluator, arguments []any) (any, error)
+}
+
+// Arity is the fewest arguments the function takes
+func (n *NativeFunction) Arity() int {
+	return n.arity
+}
+
+// acceptsArguments reports whether the function can be called with count
+// arguments
+func (n *NativeFunction) acceptsArguments(count int) bool {
+	return count >= n.arity && count <= max(n.arity, n.maxArity)
+}
+
+func (n *NativeFunction) Call(evaluator *Evaluator, arguments []any) any {
+	return n.callAt(evaluator, Token{}, arguments)
+}
+
+// callAt calls the function, reporting errors at paren
+func (n *NativeFunction) callAt(evaluator *Evaluator, paren Token, arguments []any) any {
+	value, err := n.function(evaluator, arguments)
+	if err != nil {
+		panic(RuntimeError{Token: paren, Message: err.Error()})
+	}
+	return value
+}
+
+func (n *NativeFunction) String() string {
+	return "<native fn>"
+}
+
+// nativeAssert raises a runtime error if its first argument is falsey,
+// including the optional second argument as the message
+func nativeAssert(evaluator *Evaluator, arguments []any) (any, error) {
+	if isTruthy(arguments[0]) {
+		return LoxNil{}, nil
+	}
+	if len(arguments) > 1 {
+		return nil, fmt.Errorf("Assertion failed: %s", stringify(arguments[1]))
+	}
+	return nil, errors.New("Assertion failed.")
+}
+
+// nativeLen returns the number of characters in a string or elements in an
+// array or map
+func nativeLen(evaluator *Evaluator, arguments []any) (any, error) {
+	switch value := arguments[0].(type) {
+	case LoxString:
+		return LoxNumber{value: float64(utf8.RuneCountInString(value.value))}, nil
+	case *LoxArray:
+		return LoxNumber{value: float64(len(value.elements))}, nil
+	case *LoxMap:
+		return LoxNumber{value: float64(len(value.entries))}, nil
+	}
+	return nil, errors.New("Argument to len() must be a string, an array or a map.")
+}
+
+// nativeSubstring returns the characters of a string from start up to but
+// not including end
+func nativeSubstring(evaluator *Evaluator, arguments []any) (any, error) {
+	text, ok := arguments[0].(LoxString)
+	if !ok {
+		return nil, errors.New("First argument to substring() must be a string.")
+	}
+	start, ok := arguments[1].(LoxNumber)
+	if !ok || start.value != math.Trunc(start.value) {
+		return nil, errors.New("Start index to substring() must be an integer.")
+	}
+	end, ok := arguments[2].(LoxNumber)
+	if !ok || end.value != math.Trunc(end.value) {
+		return nil, errors.New("End index to substring() must be an integer.")
+	}
+
+	runes := []rune(text.value)
+	if start.value < 0 || end.value > float64(len(runes)) || start.value > end.value {
+		return nil, fmt.Errorf("substring() range [%v, %v) is out of bounds for length %d.",
+			start.value, end.value, len(runes))
+	}
+	return LoxString{value: string(runes[int(start.value):int(end.value)])}, nil
+}
+
+// nativeIndexOf returns the index of the first occurrence of a substring, or
+// -1 if there is none
+func nativeIndexOf(evaluator *Evaluator, arguments []any) (any, error) {
+	text, ok := arguments[0].(LoxString)
+	if !ok {
+		return nil, errors.New("First argument to indexOf() must be a string.")
+	}
+	sub, ok := arguments[1].(LoxString)
+	if !ok {
+		return nil, errors.New("Second argument to indexOf() must be a string.")
+	}
+
+	index := strings.Index(text.value, sub.value)
+	if index < 0 {
+		return LoxNumber{value: -1}, nil
+	}
+	return LoxNumber{value: float64(utf8.RuneCountInString(text.value[:index]))}, nil
+}
+
+// nativeCharAt returns the character of a string at an index
+func nativeCharAt(evaluator *Evaluator, arguments []any) (any, error) {
+	text, ok := arguments[0].(LoxString)
+	if !ok {
+		return nil, errors.New("First argument to charAt() must be a string.")
+	}
+	index, ok := arguments[1].(LoxNumber)
+	if !ok || index.value != math.Trunc(index.value) {
+		return nil, errors.New("Index to charAt() must be an integer.")
+	}
+
+	runes := []rune(text.value)
+	if index.value < 0 || index.value >= float64(len(runes)) {
+		return nil, fmt.Errorf("charAt() index %v is out of bounds for length %d.", index.value, len(runes))
+	}
+	return LoxString{value: string(runes[int(index.value)])}, nil
+}
+
+// stringNative wraps a strings function as a native taking one string
+func stringNative(name string, function func(string) string) func(*Evaluator, []any) (any, error) {
+	return func(evaluator *Evaluator, arguments []any) (any, error) {
+		text, ok := arguments[0].(LoxString)
+		if !ok {
+			return nil, fmt.Errorf("Argument to %s() must be a string.", name)
+		}
+		return LoxString{value: function(text.value)}, nil
+	}
+}
+
+// nativeType names the runtime type of a value
+func nativeType(evaluator *Evaluator, arguments []any) (any, error) {
+	var name string
+	switch arguments[0].(type) {
+	case LoxNumber:
+		name = "number"
+	case LoxString:
+		name = "string"
+	case LoxBoolean:
+		name = "boolean"
+	case LoxNil:
+		name = "nil"
+	case *LoxClass:
+		name = "class"
+	case *LoxInstance:
+		name = "instance"
+	case *LoxArray:
+		name = "array"
+	case *LoxMap:
+		name = "map"
+	case LoxCallable:
+		name = "function"
+	default:
+		return nil, errors.New("Unknown runtime type.")
+	}
+	return LoxString{value: name}, nil
+}
+
+// nativeWrite prints a value like the print statement, but without the
+// trailing newline
+func nativeWrite(evaluator *Evaluator, arguments []any) (any, error) {
+	fmt.Fprint(evaluator.Out, stringify(arguments[0]))
+	return LoxNil{}, nil
+}
+
+// nativePush appends a value to an array
+func nativePush(evaluator *Evaluator, arguments []any) (any, error) {
+	array, ok := arguments[0].(*LoxArray)
+	if !ok {
+		return nil, errors.New("First argument to push() must be an array.")
+	}
+	array.elements = append(array.elements, arguments[1])
+	return LoxNil{}, nil
+}
+
+// nativePop removes and returns the last element of an array
+func nativePop(evaluator *Evaluator, arguments []any) (any, error) {
+	array, ok := arguments[0].(*LoxArray)
+	if !ok {
+		return nil, errors.New("Argument to pop() must be an array.")
+	}
+	if len(array.elements) == 0 {
+		return nil, errors.New("Can't pop from an empty array.")
+	}
+	last := array.elements[len(array.elements)-1]
+	array.elements = array.elements[:len(array.elements)-1]
+	return last, nil
+}
+
+// nativeHas reports whether a map has a key
+func nativeHas(evaluator *Evaluator, arguments []any) (any, error) {
+	m, ok := arguments[0].(*LoxMap)
+	if !ok {
+		return nil, errors.New("First argument to has() must be a map.")
+	}
+	if err := checkKey(arguments[1]); err != nil {
+		return nil, err
+	}
+	_, ok = m.entries[arguments[1]]
+	return LoxBoolean{value: ok}, nil
+}
+
+// nativeKeys returns an array of a map's keys, in the order they print
+func nativeKeys(evaluator *Evaluator, arguments []any) (any, error) {
+	m, ok := arguments[0].(*LoxMap)
+	if !ok {
+		return nil, errors.New("Argument to keys() must be a map.")
+	}
+	return &LoxArray{elements: m.sortedKeys()}, nil
+}
+
+// numberNative wraps a math function as a native taking one number
+func numberNative(name string, function func(float64) float64) func(*Evaluator, []any) (any, error) {
+	return func(evaluator *Evaluator, arguments []any) (any, error) {
+		number, ok := arguments[0].(LoxNumber)
+		if !ok {
+			return nil, fmt.Errorf("Argument to %s() must be a number.", name)
+		}
+		return LoxNumber{value: function(number.value)}, nil
+	}
+}
+
+// numbersNative wraps a function of two numbers as a native
+func numbersNative(name string, function func(a, b float64) (float64, error)) func(*Evaluator, []any) (any, error) {
+	return func(evaluator *Evaluator, arguments []any) (any, error) {
+		a, ok := arguments[0].(LoxNumber)
+		if !ok {
+			return nil, fmt.Errorf("First argument to %s() must be a number.", name)
+		}
+		b, ok := arguments[1].(LoxNumber)
+		if !ok {
+			return nil, fmt.Errorf("Second argument to %s() must be a number.", name)
+		}
+		value, err := function(a.value, b.value)
+		if err != nil {
+			return nil, err
+		}
+		return LoxNumber{value: value}, nil
+	}
+}
+
+// mathMod returns the remainder of dividing a by b, with the sign of a
+func mathMod(a, b float64) (float64, error) {
+	if b == 0 {
+		return 0, errors.New("Division by zero in mod().")
+	}
+	return math.Mod(a, b), nil
+}
+
+// realResult returns value, the result of operation on operands, unless it
+// is NaN from operands that aren't, like sqrt(-1) or (-1) ** 0.5. ** and the
+// math natives all report those as errors rather than return NaN.
+func realResult(operation string, value float64, operands ...float64) (float64, error) {
+	if !math.IsNaN(value) {
+		return value, nil
+	}
+	for _, operand := range operands {
+		if math.IsNaN(operand) {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("Result of %s is not a real number.", operation)
+}
+
+// mathPow raises a to b, rejecting results that aren't real numbers
+func mathPow(a, b float64) (float64, error) {
+	return realResult("pow()", math.Pow(a, b), a, b)
+}
+
+func mathMin(a, b float64) (float64, error) {
+	return math.Min(a, b), nil
+}
+
+func mathMax(a, b float64) (float64, error) {
+	return math.Max(a, b), nil
+}
+
+// nativeSqrt returns the square root of a non-negative number
+func nativeSqrt(evaluator *Evaluator, arguments []any) (any, error) {
+	number, ok := arguments[0].(LoxNumber)
+	if !ok {
+		return nil, errors.New("Argument to sqrt() must be a number.")
+	}
+	value, err := realResult("sqrt()", math.Sqrt(number.value), number.value)
+	if err != nil {
+		return nil, err
+	}
+	return LoxNumber{value: value}, nil
+}
+
+// nativeString converts any value to the string print would show
+func nativeString(evaluator *Evaluator, arguments []any) (any, error) {
+	return LoxString{value: stringify(arguments[0])}, nil
+}
+
+// numberConversion returns the native called name that parses a string as
+// a number. Numbers are returned as-is.
+func numberConversion(name string) func(*Evaluator, []any) (any, error) {
+	return func(evaluator *Evaluator, arguments []any) (any, error) {
+		switch value := arguments[0].(type) {
+		case LoxNumber:
+			return value, nil
+		case LoxString:
+			number, err := strconv.ParseFloat(strings.TrimSpace(value.value), 64)
+			if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+				return nil, fmt.Errorf("Can't convert '%s' to a number.", value.value)
+			}
+			return LoxNumber{value: number}, nil
+		}
+		return nil, fmt.Errorf("Argument to %s() must be a string or a number.", name)
+	}
+}
+
+// nativeClock returns the seconds since the Unix epoch, with a fractional
+// part, for timing programs
+func nativeClock(evaluator *Evaluator, arguments []any) (any, error) {
+	return LoxNumber{value: float64(time.Now().UnixNano()) / 1e9}, nil
+}
+
+// nativeRandom returns a random number from 0 up to but not including 1
+func nativeRandom(evaluator *Evaluator, arguments []any) (any, error) {
+	return LoxNumber{value: evaluator.random.Float64()}, nil
+}
+
+// nativeRandomInt returns a random integer from lo to hi, both included
+func nativeRandomInt(evaluator *Evaluator, arguments []any) (any, error) {
+	lo, ok := arguments[0].(LoxNumber)
+	if !ok || lo.value != math.Trunc(lo.value) || math.Abs(lo.value) > maxSafeInteger {
+		return nil, errors.New("Lower bound to randomInt() must be an integer.")
+	}
+	hi, ok := arguments[1].(LoxNumber)
+	if !ok || hi.value != math.Trunc(hi.value) || math.Abs(hi.value) > maxSafeInteger {
+		return nil, errors.New("Upper bound to randomInt() must be an integer.")
+	}
+	if lo.value > hi.value {
+		return nil, fmt.Errorf("randomInt() lower bound %v is greater than upper bound %v.", lo.value, hi.value)
+	}
+	n := evaluator.random.Int63n(int64(hi.value) - int64(lo.value) + 1)
+	return LoxNumber{value: lo.value + float64(n)}, nil
+}
+
+// nativeSeedRandom restarts the evaluator's random numbers from a seed, so
+// the same seed always gives the same sequence
+func nativeSeedRandom(evaluator *Evaluator, arguments []any) (any, error) {
+	seed, ok := arguments[0].(LoxNumber)
+	if !ok || seed.value != math.Trunc(seed.value) || math.Abs(seed.value) > maxSafeInteger {
+		return nil, errors.New("Seed to seedRandom() must be an integer.")
+	}
+	evaluator.random.Seed(int64(seed.value))
+	return LoxNil{}, nil
+}
+
+// maxSafeInteger is the largest integer a float64 holds exactly along with
+// every smaller one, 2^53 - 1
+const maxSafeInteger = 1<<53 - 1
+
+// nativeExit stops the program with the given exit code once Out has been
+// flushed. It unwinds like return rather than calling os.Exit, so Run hands
+// the code back to its caller.
+func nativeExit(evaluator *Evaluator, arguments []any) (any, error) {
+	code, ok := arguments[0].(LoxNumber)
+	if !ok || code.value != math.Trunc(code.value) || code.value < 0 || code.value > 255 {
+		return nil, errors.New("Exit code must be an integer between 0 and 255.")
+	}
+	if out, ok := evaluator.Out.(interface{ Flush() error }); ok {
+		if err := out.Flush(); err != nil {
+			return nil, err
+		}
+	}
+	evaluator.exited = true
+	panic(exitRequest{code: int(code.value)})
+}
+
+// nativeReadLine reads a line from the evaluator's input, without the line
+// ending, or returns nil at the end of the input
+func nativeReadLine(evaluator *Evaluator, arguments []any) (any, error) {
+	if evaluator.input == nil {
+		evaluator.input = bufio.NewReader(evaluator.In)
+	}
+	line, err := evaluator.input.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return LoxNil{}, nil
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return LoxString{value: strings.TrimSuffix(line, "\r")}, nil
+}
+
+// nativeReadNumber reads a line like readLine() and parses it like number(),
+// returning nil at the end of the input or if the line isn't a number
+func nativeReadNumber(evaluator *Evaluator, arguments []any) (any, error) {
+	line, err := nativeReadLine(evaluator, arguments)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := line.(LoxString); !ok {
+		return LoxNil{}, nil
+	}
+	number, err := numberConversion("readNumber")(evaluator, []any{line})
+	if err != nil {
+		return LoxNil{}, nil
+	}
+	return number, nil
+}
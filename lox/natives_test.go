@@ -0,0 +1,369 @@
+package lox
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// nativeTest is a program and what it should print, or the message of the
+// runtime error it should raise when err is set
+type nativeTest struct {
+	source string
+	want   string
+	err    bool
+}
+
+func runNativeTests(t *testing.T, tests []nativeTest) {
+	t.Helper()
+	for _, test := range tests {
+		if test.err {
+			_, stderr, code := run(t, test.source)
+			message, _, _ := strings.Cut(stderr, "\n")
+			if code != ExitRuntimeError || message != test.want {
+				t.Errorf("run(%q) exited %d, reporting:\n%s\nwant %d, reporting %q", test.source, code, stderr, ExitRuntimeError, test.want)
+			}
+		} else {
+			expectOutput(t, test.source, test.want+"\n")
+		}
+	}
+}
+
+func TestLen(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `print len("hello");`, want: "5"},
+		{source: `print len("");`, want: "0"},
+		{source: `print len("héllo");`, want: "5"},
+		{source: `print len(5);`, want: "Argument to len() must be a string, an array or a map.", err: true},
+		{source: `print len("a", "b");`, want: "Expected 1 arguments but got 2.", err: true},
+	})
+}
+
+func TestSubstring(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `print substring("hello", 1, 3);`, want: "el"},
+		{source: `print substring("hello", 0, 5);`, want: "hello"},
+		{source: `print substring("hello", 2, 2) == "";`, want: "true"},
+		{source: `print substring("héllo", 1, 2);`, want: "é"},
+		{source: `print substring("hello", 3, 2);`, want: "substring() range [3, 2) is out of bounds for length 5.", err: true},
+		{source: `print substring("hello", 0, 6);`, want: "substring() range [0, 6) is out of bounds for length 5.", err: true},
+		{source: `print substring("hello", 1.5, 2);`, want: "Start index to substring() must be an integer.", err: true},
+	})
+}
+
+func TestIndexOf(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `print indexOf("hello", "ll");`, want: "2"},
+		{source: `print indexOf("hello", "");`, want: "0"},
+		{source: `print indexOf("héllo", "l");`, want: "2"},
+		{source: `print indexOf("hello", "z");`, want: "-1"},
+		{source: `print indexOf(1, "a");`, want: "First argument to indexOf() must be a string.", err: true},
+		{source: `print indexOf("a", nil);`, want: "Second argument to indexOf() must be a string.", err: true},
+	})
+}
+
+func TestStringNatives(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `print charAt("hello", 0); print charAt("hello", 4);`, want: "h\no"},
+		{source: `print charAt("日本語", 1);`, want: "本"},
+		{source: `print charAt("héllo", 5);`, want: "charAt() index 5 is out of bounds for length 5.", err: true},
+		{source: `print charAt("hello", -1);`, want: "charAt() index -1 is out of bounds for length 5.", err: true},
+		{source: `print charAt("hello", 0.5);`, want: "Index to charAt() must be an integer.", err: true},
+		{source: `print charAt(["h"], 0);`, want: "First argument to charAt() must be a string.", err: true},
+		{source: `print toUpper("héllo"); print toLower("HÉLLO");`, want: "HÉLLO\nhéllo"},
+		{source: `print trim("  a b  "); print len(trim("   "));`, want: "a b\n0"},
+		{source: `print toUpper(1);`, want: "Argument to toUpper() must be a string.", err: true},
+		{source: `print toLower(nil);`, want: "Argument to toLower() must be a string.", err: true},
+		{source: `print trim(true);`, want: "Argument to trim() must be a string.", err: true},
+		{source: `print substring(1, 0, 1);`, want: "First argument to substring() must be a string.", err: true},
+		{source: `print substring("a", 0, "1");`, want: "End index to substring() must be an integer.", err: true},
+		{source: `print substring("日本語", 3, 4);`, want: "substring() range [3, 4) is out of bounds for length 3.", err: true},
+		{source: `print substring("a", 0);`, want: "Expected 3 arguments but got 2.", err: true},
+		{source: `print charAt("a");`, want: "Expected 2 arguments but got 1.", err: true},
+		{source: `print trim();`, want: "Expected 1 arguments but got 0.", err: true},
+		// ñ is two bytes, indices count it as one
+		{source: `var s = "añb"; print len(s); print indexOf(s, "b"); print substring(s, 1, 3);`, want: "3\n2\nñb"},
+	})
+}
+
+func TestType(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `print type(1.5);`, want: "number"},
+		{source: `print type("a");`, want: "string"},
+		{source: `print type(false);`, want: "boolean"},
+		{source: `print type(nil);`, want: "nil"},
+		{source: `fun f() {} print type(f);`, want: "function"},
+		{source: `print type(fun () {});`, want: "function"},
+		{source: `print type(clock);`, want: "function"},
+		{source: `class A {} print type(A);`, want: "class"},
+		{source: `class A {} print type(A());`, want: "instance"},
+		{source: `print type([1]);`, want: "array"},
+		{source: `print type({});`, want: "map"},
+	})
+}
+
+func TestWrite(t *testing.T) {
+	expectOutput(t, `write("a"); write("b");`, "ab")
+	expectOutput(t, `write(1); write(nil); print true;`, "1niltrue\n")
+}
+
+func TestPushPop(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `var a = []; push(a, 1); push(a, "two"); print a;`, want: "[1, two]"},
+		{source: `var a = [1, 2]; print pop(a); print a;`, want: "2\n[1]"},
+		{source: `var a = [1]; push(a, 2); print len(a);`, want: "2"},
+		{source: `print push([], 1);`, want: "nil"},
+		{source: `print pop([]);`, want: "Can't pop from an empty array.", err: true},
+		{source: `push(1, 2);`, want: "First argument to push() must be an array.", err: true},
+	})
+}
+
+func TestHasKeys(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `var m = {"a": 1, 2: nil}; print has(m, "a"); print has(m, 2); print has(m, "b");`, want: "true\ntrue\nfalse"},
+		{source: `print keys({"b": 1, "a": 2, 1: 3});`, want: "[1, a, b]"},
+		{source: `print keys({});`, want: "[]"},
+		{source: `print has([], 1);`, want: "First argument to has() must be a map.", err: true},
+		{source: `print has({}, 0/0);`, want: "Map keys can't be NaN.", err: true},
+	})
+}
+
+func TestRounding(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `print floor(1.5); print floor(-1.5); print floor(2);`, want: "1\n-2\n2"},
+		{source: `print ceil(1.2); print ceil(-1.2); print ceil(2);`, want: "2\n-1\n2"},
+		{source: `print round(2.5); print round(-2.5); print round(1.4);`, want: "3\n-3\n1"},
+		{source: `print floor("a");`, want: "Argument to floor() must be a number.", err: true},
+	})
+}
+
+func TestMod(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `print mod(7, 3);`, want: "1"},
+		{source: `print mod(-7, 3);`, want: "-1"},
+		{source: `print mod(7.5, 2);`, want: "1.5"},
+		{source: `print mod(1, 0);`, want: "Division by zero in mod().", err: true},
+	})
+}
+
+func TestMathNatives(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `print sqrt(16); print sqrt(2);`, want: "4\n1.4142135623730951"},
+		{source: `print pow(2, 10); print pow(2, -1); print pow(0, 0);`, want: "1024\n0.5\n1"},
+		{source: `print abs(-3); print abs(2.5); print abs(0);`, want: "3\n2.5\n0"},
+		{source: `print min(1, 2); print min(-1, -2);`, want: "1\n-2"},
+		{source: `print max(1, 2); print max(-1, -2);`, want: "2\n-1"},
+		// Results that aren't real numbers are errors, as with **
+		{source: `print sqrt(-1);`, want: "Result of sqrt() is not a real number.", err: true},
+		{source: `print pow(-8, 1/3);`, want: "Result of pow() is not a real number.", err: true},
+		{source: `print (-8) ** (1/3);`, want: "Result of ** is not a real number.", err: true},
+		{source: `print sqrt(0/0) == sqrt(0/0);`, want: "false"},
+		{source: `print pow(2, 0.5) == sqrt(2); print sqrt(2) == 2 ** 0.5;`, want: "true\ntrue"},
+		{source: `print PI; print floor(3.7);`, want: "3.141592653589793\n3"},
+		{source: `print sqrt("a");`, want: "Argument to sqrt() must be a number.", err: true},
+		{source: `print max(1, "a");`, want: "Second argument to max() must be a number.", err: true},
+		{source: `print min(1);`, want: "Expected 2 arguments but got 1.", err: true},
+	})
+}
+
+func TestConversions(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `print number("42") + 1;`, want: "43"},
+		{source: `print number(" 3.5 "); print number(7);`, want: "3.5\n7"},
+		{source: `print string(3.5) + "!";`, want: "3.5!"},
+		{source: `print string(nil); print string([1, "a"]);`, want: "nil\n[1, a]"},
+		{source: `print number("oops");`, want: "Can't convert 'oops' to a number.", err: true},
+		{source: `print number("inf");`, want: "Can't convert 'inf' to a number.", err: true},
+		{source: `print number(nil);`, want: "Argument to number() must be a string or a number.", err: true},
+	})
+}
+
+func TestStrNum(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		// str() is string(), for every kind of value
+		{source: `print str(3.5) + str(2);`, want: "3.52"},
+		{source: `print str("a") + str(true) + str(nil);`, want: "atruenil"},
+		{source: `fun f() {} print str(f);`, want: "<fn f>"},
+		{source: `print str(fun () {});`, want: "<fn>"},
+		{source: `print str(clock);`, want: "<native fn>"},
+		{source: `class A {} print str(A); print str(A());`, want: "A\nA instance"},
+		{source: `print str([1, "a", nil]); print str({"k": [2]});`, want: "[1, a, nil]\n{k: [2]}"},
+		{source: `print type(str(1));`, want: "string"},
+		// num() is number()
+		{source: `print num("42") + 1; print num(" -2.5 "); print num(7);`, want: "43\n-2.5\n7"},
+		{source: `print num("1e3"); print type(num("1"));`, want: "1000\nnumber"},
+		{source: `print num("oops");`, want: "Can't convert 'oops' to a number.", err: true},
+		{source: `print num(true);`, want: "Argument to num() must be a string or a number.", err: true},
+		{source: `print num(nil);`, want: "Argument to num() must be a string or a number.", err: true},
+		{source: `print num([1]);`, want: "Argument to num() must be a string or a number.", err: true},
+		{source: `print num();`, want: "Expected 1 arguments but got 0.", err: true},
+		// Round trips
+		{source: `print num(str(3.5)) == 3.5; print num(str(-0.25)) == -0.25; print num(str(1e21)) == 1e21;`, want: "true\ntrue\ntrue"},
+		{source: `print str(num("12")) == "12"; print str(num("0.1")) == "0.1";`, want: "true\ntrue"},
+	})
+}
+
+func TestReadLine(t *testing.T) {
+	var out bytes.Buffer
+	interpreter := NewInterpreter()
+	interpreter.Out = &out
+	interpreter.In = strings.NewReader("Ada\n42\nlast line without newline")
+	code := interpreter.Run(`
+		print "Hello, " + readLine() + "!";
+		print number(readLine()) + 1;
+		print readLine();
+		print readLine();`)
+	if code != ExitOK {
+		t.Fatalf("Run exited %d", code)
+	}
+	if want := "Hello, Ada!\n43\nlast line without newline\nnil\n"; out.String() != want {
+		t.Errorf("Run printed %q, want %q", out.String(), want)
+	}
+}
+
+// runWithInput runs source with in as its standard input and returns what it
+// printed and its exit code
+func runWithInput(t *testing.T, source string, in string) (string, int) {
+	t.Helper()
+	var out, errs bytes.Buffer
+	interpreter := NewInterpreter()
+	interpreter.Out = &out
+	interpreter.Err = &errs
+	interpreter.In = strings.NewReader(in)
+	code := interpreter.Run(source)
+	if code != ExitOK {
+		t.Errorf("Run exited %d, reporting:\n%s", code, errs.String())
+	}
+	return out.String(), code
+}
+
+func TestReadNumber(t *testing.T) {
+	out, _ := runWithInput(t, `
+		print readNumber() + 1;
+		print readNumber();
+		print readNumber();
+		print readNumber();`, "41\r\nnot a number\n 2.5 \n")
+	if want := "42\nnil\n2.5\nnil\n"; out != want {
+		t.Errorf("Run printed %q, want %q", out, want)
+	}
+}
+
+// guessingGame asks for guesses of 7 until one is right or the input ends
+const guessingGame = `
+	var answer = 7;
+	var tries = 0;
+	while (true) {
+		print "Guess:";
+		var guess = readNumber();
+		if (guess == nil) {
+			print "Bye!";
+			break;
+		}
+		tries = tries + 1;
+		if (guess == answer) {
+			print "Got it in " + string(tries) + "!";
+			break;
+		}
+		if (guess < answer) print "Higher."; else print "Lower.";
+	}`
+
+func TestGuessingGame(t *testing.T) {
+	out, _ := runWithInput(t, guessingGame, "3\n9\n7\n1\n")
+	if want := "Guess:\nHigher.\nGuess:\nLower.\nGuess:\nGot it in 3!\n"; out != want {
+		t.Errorf("game printed %q, want %q", out, want)
+	}
+
+	// The input ending while waiting for a guess
+	out, _ = runWithInput(t, guessingGame, "5\n")
+	if want := "Guess:\nHigher.\nGuess:\nBye!\n"; out != want {
+		t.Errorf("game cut short printed %q, want %q", out, want)
+	}
+}
+
+func TestExit(t *testing.T) {
+	stdout, stderr, code := run(t, "print 1; exit(3); print 2;")
+	if code != 3 || stdout != "1\n" || stderr != "" {
+		t.Errorf("run exited %d, printing %q, reporting %q; want 3, printing \"1\\n\"", code, stdout, stderr)
+	}
+	expectOutput(t, "fun f() { exit(0); } f(); print 2;", "")
+
+	// exit() unwinds through finally, and catch can't stop it
+	stdout, _, code = run(t, `try { exit(4); } catch (e) { print "caught"; } finally { print "finally"; } print "after";`)
+	if code != 4 || stdout != "finally\n" {
+		t.Errorf("exit in try exited %d, printing %q; want 4, printing \"finally\\n\"", code, stdout)
+	}
+	for _, source := range []string{"exit(256);", "exit(-1);", "exit(1.5);", `exit("a");`} {
+		runNativeTests(t, []nativeTest{{source: source, want: "Exit code must be an integer between 0 and 255.", err: true}})
+	}
+}
+
+func TestClock(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `print clock;`, want: "<native fn>"},
+		{source: `var start = clock(); print clock() >= start; print start > 1.7e9;`, want: "true\ntrue"},
+		{source: `
+			fun fib(n) { if (n < 2) return n; return fib(n - 1) + fib(n - 2); }
+			var start = clock();
+			print fib(20);
+			print clock() - start >= 0;`, want: "6765\ntrue"},
+		{source: `print clock(1);`, want: "Expected 0 arguments but got 1.", err: true},
+	})
+}
+
+func TestAssert(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		{source: `assert(true); assert(1, "one"); assert("", "empty"); print "passed";`, want: "passed"},
+		{source: `print assert(true);`, want: "nil"},
+		{source: `assert(false);`, want: "Assertion failed.", err: true},
+		{source: `assert(nil, "was " + "nil");`, want: "Assertion failed: was nil", err: true},
+		{source: `assert();`, want: "Expected 1 to 2 arguments but got 0.", err: true},
+		{source: `assert(true, "a", "b");`, want: "Expected 1 to 2 arguments but got 3.", err: true},
+	})
+
+	expectError(t, `
+fun check(x) {
+  assert(x > 0, "x must be positive");
+}
+check(1);
+check(-1);
+print "not reached";`, ExitRuntimeError,
+		"Assertion failed: x must be positive\n"+
+			"[line 3] in native assert()\n"+
+			"[line 3] in fn check()\n"+
+			"[line 6] in script\n")
+}
+
+func TestRandom(t *testing.T) {
+	runNativeTests(t, []nativeTest{
+		// The same seed gives the same sequence
+		{source: `seedRandom(42); print randomInt(1, 100); print randomInt(1, 100); print randomInt(1, 100); print random();`,
+			want: "76\n12\n61\n0.20881870305465913"},
+		{source: `print randomInt(5, 5);`, want: "5"},
+		{source: `
+			var ok = true;
+			for (var i = 0; i < 1000; i = i + 1) {
+				var r = random();
+				var n = randomInt(-3, 3);
+				if (r < 0 or r >= 1 or n < -3 or n > 3 or n != floor(n)) ok = false;
+			}
+			print ok;`, want: "true"},
+		{source: `print randomInt(2, 1);`, want: "randomInt() lower bound 2 is greater than upper bound 1.", err: true},
+		{source: `print randomInt(0.5, 1);`, want: "Lower bound to randomInt() must be an integer.", err: true},
+		{source: `print randomInt(0, "1");`, want: "Upper bound to randomInt() must be an integer.", err: true},
+		{source: `seedRandom(1.5);`, want: "Seed to seedRandom() must be an integer.", err: true},
+	})
+
+	// Each interpreter has its own generator, so using another in between
+	// doesn't change the sequence
+	want, _, _ := run(t, "seedRandom(7); print random(); print random();")
+	first, second := NewInterpreter(), NewInterpreter()
+	var out bytes.Buffer
+	first.Out, second.Out = &out, io.Discard
+	first.Run("seedRandom(7); print random();")
+	second.Run("seedRandom(8); print random();")
+	first.Run("print random();")
+	if out.String() != want {
+		t.Errorf("interleaved interpreter printed %q, want %q", out.String(), want)
+	}
+}
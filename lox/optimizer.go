@@ -0,0 +1,291 @@
+package lox
+
+import "math"
+
+// Fold replaces constant subexpressions in the program with their values,
+// so 2 + 3 * 4 becomes the literal 14 and !false becomes true, and returns
+// the program. Nodes are rewritten in place. An operation is only folded if
+// evaluating it succeeds and gives a finite value, so anything that would
+// fail or give infinity or NaN, like 1 / 0, is left for run time.
+func Fold(statements []Stmt) []Stmt {
+	f := folder{evaluator: NewEvaluator()}
+	f.stmts(statements)
+	return statements
+}
+
+// folder rewrites the tree bottom up. Its Visit methods return the
+// expression to use in place of the one visited.
+type folder struct {
+	evaluator *Evaluator // Evaluates operations whose operands are constant
+}
+
+func (f folder) expr(expr Expr) Expr {
+	if expr == nil {
+		return nil
+	}
+	return expr.Accept(f).(Expr)
+}
+
+func (f folder) exprs(exprs []Expr) {
+	for i, expr := range exprs {
+		exprs[i] = f.expr(expr)
+	}
+}
+
+func (f folder) stmt(stmt Stmt) {
+	if stmt != nil {
+		stmt.Accept(f)
+	}
+}
+
+func (f folder) stmts(stmts []Stmt) {
+	for _, stmt := range stmts {
+		f.stmt(stmt)
+	}
+}
+
+// constant reports whether expr is a literal, and its value
+func constant(expr Expr) (LoxLiteral, bool) {
+	literal, ok := expr.(*Literal)
+	if !ok {
+		return nil, false
+	}
+	return literal.Value, true
+}
+
+// fold evaluates expr, whose operands are all literals, and returns the
+// result as a literal, or expr itself if it can't be folded
+func (f folder) fold(expr Expr) Expr {
+	value, err := f.evaluator.Evaluate(expr)
+	if err != nil {
+		return expr
+	}
+	literal, ok := value.(LoxLiteral)
+	if !ok {
+		return expr
+	}
+	if number, ok := literal.(LoxNumber); ok && (math.IsInf(number.value, 0) || math.IsNaN(number.value)) {
+		return expr
+	}
+	return &Literal{Value: literal}
+}
+
+func (f folder) VisitLiteralExpr(literal *Literal) any {
+	return literal
+}
+
+func (f folder) VisitBinaryExpr(binary *Binary) any {
+	binary.Left = f.expr(binary.Left)
+	binary.Right = f.expr(binary.Right)
+	_, leftConstant := constant(binary.Left)
+	_, rightConstant := constant(binary.Right)
+	if !leftConstant || !rightConstant {
+		return binary
+	}
+	return f.fold(binary)
+}
+
+func (f folder) VisitInterpolationExpr(interpolation *Interpolation) any {
+	f.exprs(interpolation.Parts)
+	return interpolation
+}
+
+// VisitGroupingExpr drops the parentheses around a constant
+func (f folder) VisitGroupingExpr(grouping *Grouping) any {
+	grouping.Expression = f.expr(grouping.Expression)
+	if _, ok := constant(grouping.Expression); ok {
+		return grouping.Expression
+	}
+	return grouping
+}
+
+func (f folder) VisitUnaryExpr(unary *Unary) any {
+	unary.Right = f.expr(unary.Right)
+	if _, ok := constant(unary.Right); !ok {
+		return unary
+	}
+	return f.fold(unary)
+}
+
+// VisitLogicalExpr short-circuits on a constant left operand, since either
+// it or the right operand is the result
+func (f folder) VisitLogicalExpr(logical *Logical) any {
+	logical.Left = f.expr(logical.Left)
+	logical.Right = f.expr(logical.Right)
+	left, ok := constant(logical.Left)
+	if !ok {
+		return logical
+	}
+	if isTruthy(left) == (logical.Op._type == OR) {
+		return logical.Left
+	}
+	return logical.Right
+}
+
+func (f folder) VisitVariableExpr(variable *Variable) any {
+	return variable
+}
+
+func (f folder) VisitAssignExpr(assign *Assign) any {
+	assign.Value = f.expr(assign.Value)
+	return assign
+}
+
+func (f folder) VisitCallExpr(call *Call) any {
+	call.Callee = f.expr(call.Callee)
+	f.exprs(call.Arguments)
+	return call
+}
+
+// VisitConditionalExpr picks the branch for a constant condition
+func (f folder) VisitConditionalExpr(conditional *Conditional) any {
+	conditional.Condition = f.expr(conditional.Condition)
+	conditional.ThenBranch = f.expr(conditional.ThenBranch)
+	conditional.ElseBranch = f.expr(conditional.ElseBranch)
+	condition, ok := constant(conditional.Condition)
+	if !ok {
+		return conditional
+	}
+	if isTruthy(condition) {
+		return conditional.ThenBranch
+	}
+	return conditional.ElseBranch
+}
+
+func (f folder) VisitGetExpr(get *Get) any {
+	get.Object = f.expr(get.Object)
+	return get
+}
+
+func (f folder) VisitSetExpr(set *Set) any {
+	set.Object = f.expr(set.Object)
+	set.Value = f.expr(set.Value)
+	return set
+}
+
+func (f folder) VisitThisExpr(this *This) any {
+	return this
+}
+
+func (f folder) VisitSuperExpr(super *Super) any {
+	return super
+}
+
+func (f folder) VisitFunctionExpr(function *FunctionExpr) any {
+	f.stmts(function.Declaration.Body)
+	return function
+}
+
+func (f folder) VisitArrayLiteralExpr(array *ArrayLiteral) any {
+	f.exprs(array.Elements)
+	return array
+}
+
+func (f folder) VisitIndexExpr(index *Index) any {
+	index.Object = f.expr(index.Object)
+	index.Index = f.expr(index.Index)
+	return index
+}
+
+func (f folder) VisitIndexAssignExpr(assign *IndexAssign) any {
+	assign.Object = f.expr(assign.Object)
+	assign.Index = f.expr(assign.Index)
+	assign.Value = f.expr(assign.Value)
+	return assign
+}
+
+func (f folder) VisitMapLiteralExpr(literal *MapLiteral) any {
+	f.exprs(literal.Keys)
+	f.exprs(literal.Values)
+	return literal
+}
+
+func (f folder) VisitExpressionStmt(stmt *Expression) any {
+	stmt.Expression = f.expr(stmt.Expression)
+	return nil
+}
+
+func (f folder) VisitPrintStmt(stmt *Print) any {
+	stmt.Expression = f.expr(stmt.Expression)
+	return nil
+}
+
+func (f folder) VisitVarStmt(stmt *Var) any {
+	stmt.Initializer = f.expr(stmt.Initializer)
+	return nil
+}
+
+func (f folder) VisitBlockStmt(stmt *Block) any {
+	f.stmts(stmt.Statements)
+	return nil
+}
+
+func (f folder) VisitIfStmt(stmt *If) any {
+	stmt.Condition = f.expr(stmt.Condition)
+	f.stmt(stmt.ThenBranch)
+	f.stmt(stmt.ElseBranch)
+	return nil
+}
+
+func (f folder) VisitWhileStmt(stmt *While) any {
+	stmt.Condition = f.expr(stmt.Condition)
+	f.stmt(stmt.Body)
+	stmt.Increment = f.expr(stmt.Increment)
+	return nil
+}
+
+func (f folder) VisitFunctionStmt(stmt *Function) any {
+	f.stmts(stmt.Body)
+	return nil
+}
+
+func (f folder) VisitReturnStmt(stmt *Return) any {
+	stmt.Value = f.expr(stmt.Value)
+	return nil
+}
+
+func (f folder) VisitClassStmt(stmt *Class) any {
+	for _, method := range stmt.StaticMethods {
+		f.stmts(method.Body)
+	}
+	for _, method := range stmt.Methods {
+		f.stmts(method.Body)
+	}
+	return nil
+}
+
+func (f folder) VisitBreakStmt(stmt *Break) any {
+	return nil
+}
+
+func (f folder) VisitContinueStmt(stmt *Continue) any {
+	return nil
+}
+
+func (f folder) VisitSwitchStmt(stmt *Switch) any {
+	stmt.Subject = f.expr(stmt.Subject)
+	for i := range stmt.Cases {
+		stmt.Cases[i].Value = f.expr(stmt.Cases[i].Value)
+		f.stmts(stmt.Cases[i].Body)
+	}
+	f.stmts(stmt.Default)
+	return nil
+}
+
+func (f folder) VisitThrowStmt(stmt *Throw) any {
+	stmt.Value = f.expr(stmt.Value)
+	return nil
+}
+
+func (f folder) VisitTryStmt(stmt *Try) any {
+	f.stmts(stmt.Body)
+	f.stmts(stmt.Catch)
+	f.stmts(stmt.Finally)
+	return nil
+}
+
+func (f folder) VisitForEachStmt(stmt *ForEach) any {
+	stmt.Collection = f.expr(stmt.Collection)
+	f.stmt(stmt.Body)
+	return nil
+}
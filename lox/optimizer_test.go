@@ -0,0 +1,92 @@
+package lox
+
+import (
+	"bytes"
+	"testing"
+)
+
+// folded parses source, a single expression, folds it and returns it printed
+// by AstPrinter
+func folded(t *testing.T, source string) string {
+	t.Helper()
+	tokens, _ := Tokenize("print " + source + ";")
+	parser := NewParser(tokens)
+	statements, errors := parser.Parse()
+	if len(errors) > 0 {
+		t.Fatalf("Parse(%q) errors: %v", source, errors)
+	}
+	Fold(statements)
+	return (AstPrinter{}).Print(statements[0].(*Print).Expression)
+}
+
+func TestFold(t *testing.T) {
+	for source, want := range map[string]string{
+		"2 + 3 * 4":       "14.0",
+		"!false":          "true",
+		`"a" + "b"`:       "ab",
+		"(1 + 2) * x":     "(* 3.0 x)",
+		"x + 1 * 2":       "(+ x 2.0)",
+		"true or x":       "true",
+		"false and x":     "false",
+		"nil or x":        "x",
+		"f(1 + 1)":        "(call f 2.0)",
+		"1 / 0":           "(/ 1.0 0.0)",
+		"-(1 / 0) + 1":    "(+ (- (group (/ 1.0 0.0))) 1.0)",
+		`1 + "a"`:         "(+ 1.0 a)",
+		"x = 2 * 3":       "(= x 6.0)",
+		"(x) ? 1 + 1 : 2": "(?: (group x) 2.0 2.0)",
+	} {
+		if got := folded(t, source); got != want {
+			t.Errorf("folding %s gave %s, want %s", source, got, want)
+		}
+	}
+}
+
+func TestFoldSameResult(t *testing.T) {
+	for _, source := range []string{
+		`var x = 3; print 2 + 3 * 4; print !false; print (1 + 2) * x; print "a" + "b" + x;`,
+		`fun f(n) { return n * (2 + 2); } print f(1 + 1);`,
+		`var a = nil or "default"; print a; print false and undefined;`,
+		`print 1 / 0;`,
+		`print 1 + "a";`,
+	} {
+		wantOut, wantErr, wantCode := run(t, source)
+		interpreter := NewInterpreter()
+		interpreter.FoldConstants = true
+		gotOut, gotErr, gotCode := runWith(t, interpreter, source)
+		if gotOut != wantOut || gotErr != wantErr || gotCode != wantCode {
+			t.Errorf("folded %q exited %d printing %q, reporting %q; unfolded exited %d printing %q, reporting %q",
+				source, gotCode, gotOut, gotErr, wantCode, wantOut, wantErr)
+		}
+	}
+}
+
+// evaluateWith runs source as a single expression with interpreter,
+// returning what it printed, what it reported and its exit code
+func evaluateWith(interpreter *Interpreter, source string) (stdout, stderr string, code int) {
+	var out, errs bytes.Buffer
+	interpreter.Out = &out
+	interpreter.Err = &errs
+	code = interpreter.RunExpression(source)
+	return out.String(), errs.String(), code
+}
+
+func TestFoldExpressionSameResult(t *testing.T) {
+	for _, source := range []string{
+		"2 + 3 * 4",
+		`"a" + "b"`,
+		"nil or 1 + 1",
+		"-(2 - 3) > 0 ? 1 : 2",
+		"1 / 0",
+		`1 + "a"`,
+	} {
+		wantOut, wantErr, wantCode := evaluateWith(NewInterpreter(), source)
+		interpreter := NewInterpreter()
+		interpreter.FoldConstants = true
+		gotOut, gotErr, gotCode := evaluateWith(interpreter, source)
+		if gotOut != wantOut || gotErr != wantErr || gotCode != wantCode {
+			t.Errorf("folded %q exited %d printing %q, reporting %q; unfolded exited %d printing %q, reporting %q",
+				source, gotCode, gotOut, gotErr, wantCode, wantOut, wantErr)
+		}
+	}
+}
@@ -0,0 +1,948 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseError is a syntax error found while parsing, at Token
+type ParseError struct {
+	Token   Token
+	Message string
+}
+
+func (err ParseError) Error() string {
+	return fmt.Sprintf("[line %d] Error%s: %s", err.Token.line, where(err.Token), err.Message)
+}
+
+// DefaultMaxDepth is how deeply statements and expressions may nest by default
+const DefaultMaxDepth = 1024
+
+type Parser struct {
+	tokens  []Token
+	current int
+	errors  []ParseError
+
+	// MaxDepth limits nesting so pathological input can't overflow the stack
+	MaxDepth int
+	depth    int
+
+	// MaxErrors is how many errors to report before giving up on the rest
+	// of the tokens, 0 for no limit
+	MaxErrors int
+
+	// spans, when not nil, records where each statement in a statement
+	// list and each method was in the source, for the formatter
+	spans map[Stmt]span
+}
+
+// span is where a statement starts and ends in the source, as byte offsets,
+// along with the line it ends on
+type span struct {
+	start, end, endLine int
+}
+
+func NewParser(tokens []Token) Parser {
+	return Parser{
+		tokens:    tokens,
+		MaxDepth:  DefaultMaxDepth,
+		MaxErrors: DefaultMaxErrors,
+	}
+}
+
+// tooDeep unwinds the whole parse once nesting exceeds MaxDepth. Recovering
+// at the enclosing statement would only report every unclosed level again.
+type tooDeep struct{}
+
+// enter records one more level of nesting, failing if there are too many.
+// Each call must be paired with a deferred leave.
+func (p *Parser) enter(what string) {
+	p.depth++
+	if p.depth > p.MaxDepth {
+		p.fail(p.peek(), fmt.Sprintf("%s too deeply nested (max %d).", what, p.MaxDepth))
+		panic(tooDeep{})
+	}
+}
+
+func (p *Parser) leave() {
+	p.depth--
+}
+
+// Parse returns the statements in the program along with every syntax error
+// found. The statements are only meaningful if there were no errors.
+func (p *Parser) Parse() (statements []Stmt, errs []ParseError) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(tooDeep); !ok {
+				panic(r)
+			}
+			errs = p.errors
+		}
+	}()
+
+	for !p.isAtEnd() {
+		if tooManyErrors(len(p.errors), p.MaxErrors) {
+			p.fail(p.peek(), "Too many errors.")
+			break
+		}
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements, p.errors
+}
+
+// ParseExpression parses the tokens as a single expression with nothing,
+// not even a semicolon, after it.
+func (p *Parser) ParseExpression() (expr Expr, errs []ParseError) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r.(type) {
+			case ParseError, tooDeep:
+				expr, errs = nil, p.errors
+			default:
+				panic(r)
+			}
+		}
+	}()
+
+	expr = p.expression()
+	if !p.isAtEnd() {
+		panic(p.fail(p.peek(), "Expect end of expression."))
+	}
+	return expr, p.errors
+}
+
+// recordSpan records that stmt ran from start to the previous token, if
+// spans are being recorded and stmt parsed
+func (p *Parser) recordSpan(start Token, stmt Stmt) {
+	if p.spans == nil || stmt == nil {
+		return
+	}
+	end := p.previous()
+	p.spans[stmt] = span{start: start.startOffset, end: end.endOffset, endLine: end.line}
+}
+
+// declaration parses a single declaration. After a syntax error it
+// synchronizes to the next statement and returns nil.
+func (p *Parser) declaration() (stmt Stmt) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(ParseError); !ok {
+				panic(r)
+			}
+			p.synchronize()
+			stmt = nil
+		}
+	}()
+	start := p.peek()
+	defer func() { p.recordSpan(start, stmt) }()
+
+	if p.match(CLASS) {
+		return p.classDeclaration()
+	}
+	// Without a name, "fun" starts an anonymous function expression
+	if p.check(FUN) && p.checkNext(IDENTIFIER) {
+		p.advance()
+		return p.function("function")
+	}
+	if p.match(VAR) {
+		return p.varDeclaration()
+	}
+	if p.match(CONST) {
+		return p.constDeclaration()
+	}
+	return p.statement()
+}
+
+func (p *Parser) classDeclaration() Stmt {
+	name := p.consume(IDENTIFIER, "Expect class name.")
+
+	var superclass *Variable
+	if p.match(LESS) {
+		p.consume(IDENTIFIER, "Expect superclass name.")
+		superclass = &Variable{Name: p.previous()}
+	}
+
+	p.consume(LEFT_BRACE, "Expect '{' before class body.")
+
+	var methods, staticMethods []*Function
+	for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
+		start := p.peek()
+		static := p.match(CLASS)
+		method := p.function("method")
+		p.recordSpan(start, method)
+		if static {
+			staticMethods = append(staticMethods, method)
+		} else {
+			methods = append(methods, method)
+		}
+	}
+
+	p.consume(RIGHT_BRACE, "Expect '}' after class body.")
+	return &Class{Name: name, Superclass: superclass, Methods: methods, StaticMethods: staticMethods}
+}
+
+func (p *Parser) function(kind string) *Function {
+	name := p.consume(IDENTIFIER, "Expect "+kind+" name.")
+
+	// A method without a parameter list is a getter
+	if kind == "method" && p.match(LEFT_BRACE) {
+		body := p.block()
+		return &Function{Name: name, Body: body, IsGetter: true}
+	}
+
+	p.consume(LEFT_PAREN, "Expect '(' after "+kind+" name.")
+	params, body := p.functionBody(kind)
+	return &Function{Name: name, Params: params, Body: body}
+}
+
+// functionBody parses a parameter list after the opening '(', then the body
+func (p *Parser) functionBody(kind string) ([]Token, []Stmt) {
+	var params []Token
+	if !p.check(RIGHT_PAREN) {
+		for {
+			if len(params) >= 255 {
+				p.fail(p.peek(), "Can't have more than 255 parameters.")
+			}
+			params = append(params, p.consume(IDENTIFIER, "Expect parameter name."))
+			if !p.match(COMMA) {
+				break
+			}
+		}
+	}
+	p.consume(RIGHT_PAREN, "Expect ')' after parameters.")
+
+	p.consume(LEFT_BRACE, "Expect '{' before "+kind+" body.")
+	body := p.block()
+	return params, body
+}
+
+func (p *Parser) varDeclaration() Stmt {
+	return p.varInitializer(p.consume(IDENTIFIER, "Expect variable name."))
+}
+
+// varInitializer parses the rest of a var declaration after its name
+func (p *Parser) varInitializer(name Token) Stmt {
+	var initializer Expr
+	if p.match(EQUAL) {
+		initializer = p.expression()
+	}
+
+	p.consume(SEMICOLON, "Expect ';' after variable declaration.")
+	return &Var{Name: name, Initializer: initializer}
+}
+
+// constDeclaration parses a const declaration, which must be initialized
+func (p *Parser) constDeclaration() Stmt {
+	name := p.consume(IDENTIFIER, "Expect constant name.")
+	p.consume(EQUAL, "Expect '=' after constant name.")
+	initializer := p.expression()
+	p.consume(SEMICOLON, "Expect ';' after constant declaration.")
+	return &Var{Name: name, Initializer: initializer, Const: true}
+}
+
+func (p *Parser) statement() Stmt {
+	p.enter("Statement")
+	defer p.leave()
+	if p.match(BREAK) {
+		keyword := p.previous()
+		p.consume(SEMICOLON, "Expect ';' after 'break'.")
+		return &Break{Keyword: keyword}
+	}
+	if p.match(CONTINUE) {
+		keyword := p.previous()
+		p.consume(SEMICOLON, "Expect ';' after 'continue'.")
+		return &Continue{Keyword: keyword}
+	}
+	if p.match(DO) {
+		return p.doWhileStatement()
+	}
+	if p.match(FOR) {
+		return p.forStatement()
+	}
+	if p.match(IF) {
+		return p.ifStatement()
+	}
+	if p.match(PRINT) {
+		return p.printStatement()
+	}
+	if p.match(RETURN) {
+		return p.returnStatement()
+	}
+	if p.match(SWITCH) {
+		return p.switchStatement()
+	}
+	if p.match(THROW) {
+		keyword := p.previous()
+		value := p.expression()
+		p.consume(SEMICOLON, "Expect ';' after thrown value.")
+		return &Throw{Keyword: keyword, Value: value}
+	}
+	if p.match(TRY) {
+		return p.tryStatement()
+	}
+	if p.match(WHILE) {
+		return p.whileStatement()
+	}
+	if p.match(LEFT_BRACE) {
+		return &Block{Statements: p.block()}
+	}
+	return p.expressionStatement()
+}
+
+// forStatement desugars a for loop into a while loop inside a block
+func (p *Parser) forStatement() Stmt {
+	p.consume(LEFT_PAREN, "Expect '(' after 'for'.")
+
+	var initializer Stmt
+	if p.match(SEMICOLON) {
+		initializer = nil
+	} else if p.match(VAR) {
+		name := p.consume(IDENTIFIER, "Expect variable name.")
+		if p.match(IN) {
+			return p.forEachStatement(name)
+		}
+		initializer = p.varInitializer(name)
+	} else {
+		initializer = p.expressionStatement()
+	}
+
+	var condition Expr
+	if !p.check(SEMICOLON) {
+		condition = p.expression()
+	}
+	p.consume(SEMICOLON, "Expect ';' after loop condition.")
+
+	var increment Expr
+	if !p.check(RIGHT_PAREN) {
+		increment = p.expression()
+	}
+	p.consume(RIGHT_PAREN, "Expect ')' after for clauses.")
+
+	body := p.statement()
+
+	if condition == nil {
+		condition = &Literal{Value: LoxBoolean{value: true}}
+	}
+	body = &While{Condition: condition, Body: body, Increment: increment}
+	if initializer != nil {
+		body = &Block{Statements: []Stmt{initializer, body}}
+	}
+	return body
+}
+
+// forEachStatement parses the rest of a for-in loop after "in"
+func (p *Parser) forEachStatement(name Token) Stmt {
+	in := p.previous()
+	collection := p.expression()
+	p.consume(RIGHT_PAREN, "Expect ')' after for-in collection.")
+	body := p.statement()
+	return &ForEach{Name: name, In: in, Collection: collection, Body: body}
+}
+
+func (p *Parser) ifStatement() Stmt {
+	p.consume(LEFT_PAREN, "Expect '(' after 'if'.")
+	condition := p.expression()
+	p.consume(RIGHT_PAREN, "Expect ')' after if condition.")
+
+	thenBranch := p.statement()
+	var elseBranch Stmt
+	if p.match(ELSE) {
+		elseBranch = p.statement()
+	}
+	return &If{Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch}
+}
+
+func (p *Parser) printStatement() Stmt {
+	value := p.expression()
+	p.consume(SEMICOLON, "Expect ';' after value.")
+	return &Print{Expression: value}
+}
+
+func (p *Parser) returnStatement() Stmt {
+	keyword := p.previous()
+	var value Expr
+	if !p.check(SEMICOLON) {
+		value = p.expression()
+	}
+	p.consume(SEMICOLON, "Expect ';' after return value.")
+	return &Return{Keyword: keyword, Value: value}
+}
+
+func (p *Parser) whileStatement() Stmt {
+	p.consume(LEFT_PAREN, "Expect '(' after 'while'.")
+	condition := p.expression()
+	p.consume(RIGHT_PAREN, "Expect ')' after condition.")
+	body := p.statement()
+	return &While{Condition: condition, Body: body}
+}
+
+func (p *Parser) doWhileStatement() Stmt {
+	body := p.statement()
+	p.consume(WHILE, "Expect 'while' after do-while body.")
+	p.consume(LEFT_PAREN, "Expect '(' after 'while'.")
+	condition := p.expression()
+	p.consume(RIGHT_PAREN, "Expect ')' after condition.")
+	p.consume(SEMICOLON, "Expect ';' after do-while condition.")
+	return &While{Condition: condition, Body: body, DoWhile: true}
+}
+
+func (p *Parser) switchStatement() Stmt {
+	p.consume(LEFT_PAREN, "Expect '(' after 'switch'.")
+	subject := p.expression()
+	p.consume(RIGHT_PAREN, "Expect ')' after switch value.")
+	p.consume(LEFT_BRACE, "Expect '{' before switch cases.")
+
+	p.enter("Block")
+	defer p.leave()
+
+	stmt := &Switch{Subject: subject}
+	for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
+		if p.match(CASE) {
+			if stmt.Default != nil {
+				p.fail(p.previous(), "Can't have a case after the default case.")
+			}
+			value := p.expression()
+			p.consume(COLON, "Expect ':' after case value.")
+			stmt.Cases = append(stmt.Cases, SwitchCase{Value: value, Body: p.caseBody()})
+		} else if p.match(DEFAULT) {
+			if stmt.Default != nil {
+				p.fail(p.previous(), "Can't have more than one default case.")
+			}
+			p.consume(COLON, "Expect ':' after 'default'.")
+			stmt.Default = p.caseBody()
+		} else {
+			panic(p.fail(p.peek(), "Expect 'case' or 'default' in switch."))
+		}
+	}
+	p.consume(RIGHT_BRACE, "Expect '}' after switch cases.")
+	return stmt
+}
+
+func (p *Parser) tryStatement() Stmt {
+	keyword := p.previous()
+	p.consume(LEFT_BRACE, "Expect '{' after 'try'.")
+	stmt := &Try{Body: p.block()}
+	if p.match(CATCH) {
+		p.consume(LEFT_PAREN, "Expect '(' after 'catch'.")
+		stmt.CatchName = p.consume(IDENTIFIER, "Expect exception variable name.")
+		p.consume(RIGHT_PAREN, "Expect ')' after exception variable.")
+		p.consume(LEFT_BRACE, "Expect '{' before catch body.")
+		stmt.Catch = append([]Stmt{}, p.block()...)
+	}
+	if p.match(FINALLY) {
+		p.consume(LEFT_BRACE, "Expect '{' after 'finally'.")
+		stmt.Finally = append([]Stmt{}, p.block()...)
+	}
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.fail(keyword, "Expect 'catch' or 'finally' after try block.")
+	}
+	return stmt
+}
+
+// caseBody parses the statements of a switch arm. It never returns nil, so
+// an empty default arm is still told apart from a missing one.
+func (p *Parser) caseBody() []Stmt {
+	statements := []Stmt{}
+	for !p.check(CASE) && !p.check(DEFAULT) && !p.check(RIGHT_BRACE) && !p.isAtEnd() {
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
+
+func (p *Parser) block() []Stmt {
+	p.enter("Block")
+	defer p.leave()
+
+	var statements []Stmt
+	for !p.check(RIGHT_BRACE) && !p.isAtEnd() {
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
+	}
+	p.consume(RIGHT_BRACE, "Expect '}' after block.")
+	return statements
+}
+
+func (p *Parser) expressionStatement() Stmt {
+	expr := p.expression()
+	if function, ok := expr.(*FunctionExpr); ok {
+		// It can never be called, so it was probably meant as a declaration
+		p.fail(function.Keyword, "Expect function name.")
+	}
+	p.consume(SEMICOLON, "Expect ';' after expression.")
+	return &Expression{Expression: expr}
+}
+
+func (p *Parser) expression() Expr {
+	return p.comma()
+}
+
+// comma parses the sequence operator, which has the lowest precedence of all.
+// Call arguments are parsed at assignment so they aren't swallowed by it.
+func (p *Parser) comma() Expr {
+	expr := p.assignment()
+	for p.match(COMMA) {
+		op := p.previous()
+		right := p.assignment()
+		expr = &Binary{Left: expr, Op: op, Right: right}
+	}
+	return expr
+}
+
+// assignment is where every nested expression, be it a grouping, an
+// argument or an element, comes back in, so it guards the nesting depth
+func (p *Parser) assignment() Expr {
+	p.enter("Expression")
+	defer p.leave()
+
+	expr := p.conditional()
+
+	if p.match(EQUAL, PLUS_EQUAL, MINUS_EQUAL, STAR_EQUAL, SLASH_EQUAL) {
+		equals := p.previous()
+		value := p.assignment()
+		if equals._type != EQUAL {
+			value = compoundValue(expr, equals, value)
+		}
+		return p.assignTo(expr, equals, value, "Invalid assignment target.")
+	}
+	return expr
+}
+
+// assignTo builds the assignment of value to target. If target can't be
+// assigned to, it reports message at op and returns target unchanged.
+func (p *Parser) assignTo(target Expr, op Token, value Expr, message string) Expr {
+	switch target := target.(type) {
+	case *Variable:
+		return &Assign{Name: target.Name, Value: value}
+	case *Get:
+		return &Set{Object: target.Object, Name: target.Name, Value: value}
+	case *Index:
+		return &IndexAssign{Object: target.Object, Bracket: target.Bracket, Index: target.Index, Value: value}
+	}
+	// Report but don't unwind, the parser isn't confused
+	p.fail(op, message)
+	return target
+}
+
+// compoundOperators maps each compound assignment to its binary operator
+var compoundOperators = map[TokenType]TokenType{
+	PLUS_EQUAL:  PLUS,
+	MINUS_EQUAL: MINUS,
+	STAR_EQUAL:  STAR,
+	SLASH_EQUAL: SLASH,
+}
+
+// compoundValue desugars the value of target op= value into target op value.
+// The target expression is reused as the left operand, so a field or index
+// target's object is evaluated twice.
+func compoundValue(target Expr, equals Token, value Expr) Expr {
+	op := equals
+	op._type = compoundOperators[equals._type]
+	op.lexeme = strings.TrimSuffix(equals.lexeme, "=")
+	return &Binary{Left: target, Op: op, Right: value}
+}
+
+// conditional parses the right-associative ternary operator
+func (p *Parser) conditional() Expr {
+	expr := p.or()
+	if p.match(QUESTION) {
+		p.enter("Expression")
+		defer p.leave()
+
+		thenBranch := p.expression()
+		p.consume(COLON, "Expect ':' after then branch of conditional expression.")
+		elseBranch := p.conditional()
+		expr = &Conditional{Condition: expr, ThenBranch: thenBranch, ElseBranch: elseBranch}
+	}
+	return expr
+}
+
+func (p *Parser) or() Expr {
+	expr := p.and()
+	for p.match(OR) {
+		op := p.previous()
+		right := p.and()
+		expr = &Logical{Left: expr, Op: op, Right: right}
+	}
+	return expr
+}
+
+func (p *Parser) and() Expr {
+	expr := p.equality()
+	for p.match(AND) {
+		op := p.previous()
+		right := p.equality()
+		expr = &Logical{Left: expr, Op: op, Right: right}
+	}
+	return expr
+}
+
+func (p *Parser) equality() Expr {
+	if p.match(BANG_EQUAL, EQUAL_EQUAL) {
+		return p.missingLeftOperand(p.comparison)
+	}
+	expr := p.comparison()
+	for p.match(BANG_EQUAL, EQUAL_EQUAL) {
+		op := p.previous()
+		right := p.comparison()
+		expr = &Binary{Left: expr, Op: op, Right: right}
+	}
+	return expr
+}
+
+func (p *Parser) comparison() Expr {
+	if p.match(GREATER, GREATER_EQUAL, LESS, LESS_EQUAL) {
+		return p.missingLeftOperand(p.bitOr)
+	}
+	expr := p.bitOr()
+	for p.match(GREATER, GREATER_EQUAL, LESS, LESS_EQUAL) {
+		op := p.previous()
+		right := p.bitOr()
+		expr = &Binary{Left: expr, Op: op, Right: right}
+	}
+	return expr
+}
+
+// The bitwise operators bind tighter than comparisons, as in Python, so
+// a & 1 == 0 tests the low bit.
+func (p *Parser) bitOr() Expr {
+	if p.match(PIPE) {
+		return p.missingLeftOperand(p.bitXor)
+	}
+	expr := p.bitXor()
+	for p.match(PIPE) {
+		op := p.previous()
+		right := p.bitXor()
+		expr = &Binary{Left: expr, Op: op, Right: right}
+	}
+	return expr
+}
+
+func (p *Parser) bitXor() Expr {
+	if p.match(CARET) {
+		return p.missingLeftOperand(p.bitAnd)
+	}
+	expr := p.bitAnd()
+	for p.match(CARET) {
+		op := p.previous()
+		right := p.bitAnd()
+		expr = &Binary{Left: expr, Op: op, Right: right}
+	}
+	return expr
+}
+
+func (p *Parser) bitAnd() Expr {
+	if p.match(AMPERSAND) {
+		return p.missingLeftOperand(p.shift)
+	}
+	expr := p.shift()
+	for p.match(AMPERSAND) {
+		op := p.previous()
+		right := p.shift()
+		expr = &Binary{Left: expr, Op: op, Right: right}
+	}
+	return expr
+}
+
+func (p *Parser) shift() Expr {
+	if p.match(LESS_LESS, GREATER_GREATER) {
+		return p.missingLeftOperand(p.term)
+	}
+	expr := p.term()
+	for p.match(LESS_LESS, GREATER_GREATER) {
+		op := p.previous()
+		right := p.term()
+		expr = &Binary{Left: expr, Op: op, Right: right}
+	}
+	return expr
+}
+
+func (p *Parser) term() Expr {
+	// A leading '-' is negation, so only '+' is missing an operand
+	if p.match(PLUS) {
+		return p.missingLeftOperand(p.factor)
+	}
+	expr := p.factor()
+	for p.match(MINUS, PLUS) {
+		op := p.previous()
+		right := p.factor()
+		expr = &Binary{Left: expr, Op: op, Right: right}
+	}
+	return expr
+}
+
+func (p *Parser) factor() Expr {
+	if p.match(SLASH, STAR) {
+		return p.missingLeftOperand(p.unary)
+	}
+	expr := p.unary()
+	for p.match(SLASH, STAR) {
+		op := p.previous()
+		right := p.unary()
+		expr = &Binary{Left: expr, Op: op, Right: right}
+	}
+	return expr
+}
+
+// missingLeftOperand is an error production for a binary operator at the
+// start of an expression. It reports the error, then parses and discards the
+// right operand so parsing can carry on.
+func (p *Parser) missingLeftOperand(operand func() Expr) Expr {
+	op := p.previous()
+	p.fail(op, "Binary operator '"+op.lexeme+"' requires a left-hand operand.")
+	operand()
+	return &Literal{Value: LoxNil{}}
+}
+
+func (p *Parser) unary() Expr {
+	if p.match(BANG, MINUS) {
+		op := p.previous()
+
+		p.enter("Expression")
+		defer p.leave()
+		right := p.unary()
+		return &Unary{Op: op, Right: right}
+	}
+	if p.match(PLUS_PLUS, MINUS_MINUS) {
+		op := p.previous()
+
+		p.enter("Expression")
+		defer p.leave()
+		target := p.unary()
+
+		// ++x desugars to x = x ++ 1, a binary ++ that only adds numbers
+		value := &Binary{Left: target, Op: op, Right: &Literal{Value: LoxNumber{value: 1}}}
+		return p.assignTo(target, op, value, "Invalid increment target.")
+	}
+	return p.power()
+}
+
+// power binds tighter than a unary operator on its left, so -2 ** 2 is
+// -(2 ** 2), and is right-associative through the unary exponent.
+func (p *Parser) power() Expr {
+	if p.match(STAR_STAR) {
+		return p.missingLeftOperand(p.unary)
+	}
+	expr := p.call()
+	if p.match(STAR_STAR) {
+		op := p.previous()
+
+		p.enter("Expression")
+		defer p.leave()
+		right := p.unary()
+		expr = &Binary{Left: expr, Op: op, Right: right}
+	}
+	return expr
+}
+
+func (p *Parser) call() Expr {
+	expr := p.primary()
+	for {
+		if p.match(LEFT_PAREN) {
+			expr = p.finishCall(expr)
+		} else if p.match(DOT) {
+			name := p.consume(IDENTIFIER, "Expect property name after '.'.")
+			expr = &Get{Object: expr, Name: name}
+		} else if p.match(LEFT_BRACKET) {
+			index := p.expression()
+			bracket := p.consume(RIGHT_BRACKET, "Expect ']' after index.")
+			expr = &Index{Object: expr, Bracket: bracket, Index: index}
+		} else {
+			break
+		}
+	}
+	return expr
+}
+
+func (p *Parser) finishCall(callee Expr) Expr {
+	var arguments []Expr
+	if !p.check(RIGHT_PAREN) {
+		for {
+			if len(arguments) >= 255 {
+				p.fail(p.peek(), "Can't have more than 255 arguments.")
+			}
+			arguments = append(arguments, p.assignment())
+			if !p.match(COMMA) {
+				break
+			}
+		}
+	}
+	paren := p.consume(RIGHT_PAREN, "Expect ')' after arguments.")
+	return &Call{Callee: callee, Paren: paren, Arguments: arguments}
+}
+
+func (p *Parser) primary() Expr {
+	switch {
+	case p.match(FALSE):
+		return &Literal{Value: LoxBoolean{value: false}}
+	case p.match(TRUE):
+		return &Literal{Value: LoxBoolean{value: true}}
+	case p.match(NIL):
+		return &Literal{Value: LoxNil{}}
+	case p.match(NUMBER, STRING):
+		return &Literal{Value: p.previous().literal, Token: p.previous()}
+	case p.match(INTERPOLATION):
+		return p.interpolation()
+	case p.match(SUPER):
+		keyword := p.previous()
+		p.consume(DOT, "Expect '.' after 'super'.")
+		method := p.consume(IDENTIFIER, "Expect superclass method name.")
+		return &Super{Keyword: keyword, Method: method}
+	case p.match(FUN):
+		keyword := p.previous()
+		p.consume(LEFT_PAREN, "Expect '(' after 'fun'.")
+		params, body := p.functionBody("function")
+		return &FunctionExpr{Keyword: keyword, Declaration: &Function{Params: params, Body: body}}
+	case p.match(THIS):
+		return &This{Keyword: p.previous()}
+	case p.match(IDENTIFIER):
+		return &Variable{Name: p.previous()}
+	case p.match(LEFT_BRACKET):
+		return p.arrayLiteral()
+	case p.match(LEFT_BRACE):
+		// statement() has already taken a leading brace as a block
+		return p.mapLiteral()
+	case p.match(LEFT_PAREN):
+		expr := p.expression()
+		p.consume(RIGHT_PAREN, "Expect ')' after expression.")
+		return &Grouping{Expression: expr}
+	}
+	panic(p.fail(p.peek(), "Expect expression."))
+}
+
+// arrayLiteral parses the elements of an array after the opening bracket.
+// Like call arguments they are assignments, so commas separate them.
+func (p *Parser) arrayLiteral() Expr {
+	bracket := p.previous()
+	var elements []Expr
+	if !p.check(RIGHT_BRACKET) {
+		for {
+			elements = append(elements, p.assignment())
+			if !p.match(COMMA) {
+				break
+			}
+		}
+	}
+	p.consume(RIGHT_BRACKET, "Expect ']' after array elements.")
+	return &ArrayLiteral{Bracket: bracket, Elements: elements}
+}
+
+// mapLiteral parses the entries of a map after the opening brace
+func (p *Parser) mapLiteral() Expr {
+	literal := &MapLiteral{Brace: p.previous()}
+	if !p.check(RIGHT_BRACE) {
+		for {
+			literal.Keys = append(literal.Keys, p.assignment())
+			p.consume(COLON, "Expect ':' after map key.")
+			literal.Values = append(literal.Values, p.assignment())
+			if !p.match(COMMA) {
+				break
+			}
+		}
+	}
+	p.consume(RIGHT_BRACE, "Expect '}' after map entries.")
+	return literal
+}
+
+// interpolation parses the segments of an interpolated string, the first of
+// which has just been consumed. The scanner ends the string with a STRING.
+func (p *Parser) interpolation() Expr {
+	parts := []Expr{&Literal{Value: p.previous().literal}}
+	for {
+		if (p.check(INTERPOLATION) || p.check(STRING)) && strings.HasPrefix(p.peek().lexeme, "}") {
+			// The segment after "}" with nothing before it
+			p.fail(p.peek(), "Expect expression between '${' and '}'.")
+		} else {
+			parts = append(parts, p.expression())
+		}
+		if p.match(INTERPOLATION) {
+			parts = append(parts, &Literal{Value: p.previous().literal})
+			continue
+		}
+		p.consume(STRING, "Expect end of string interpolation.")
+		parts = append(parts, &Literal{Value: p.previous().literal})
+		return &Interpolation{Parts: parts}
+	}
+}
+
+func (p *Parser) match(types ...TokenType) bool {
+	for _, t := range types {
+		if p.check(t) {
+			p.advance()
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Parser) consume(t TokenType, message string) Token {
+	if p.check(t) {
+		return p.advance()
+	}
+	panic(p.fail(p.peek(), message))
+}
+
+func (p *Parser) check(t TokenType) bool {
+	if p.isAtEnd() {
+		return false
+	}
+	return p.peek()._type == t
+}
+
+// checkNext looks at the token after the current one
+func (p *Parser) checkNext(t TokenType) bool {
+	if p.isAtEnd() || p.tokens[p.current+1]._type == EOF {
+		return false
+	}
+	return p.tokens[p.current+1]._type == t
+}
+
+func (p *Parser) advance() Token {
+	if !p.isAtEnd() {
+		p.current++
+	}
+	return p.previous()
+}
+
+func (p *Parser) isAtEnd() bool {
+	return p.peek()._type == EOF
+}
+
+func (p *Parser) peek() Token {
+	return p.tokens[p.current]
+}
+
+func (p *Parser) previous() Token {
+	return p.tokens[p.current-1]
+}
+
+// fail records a syntax error at tok. Callers panic with the result when the
+// parser can't continue.
+func (p *Parser) fail(tok Token, message string) ParseError {
+	err := ParseError{Token: tok, Message: message}
+	p.errors = append(p.errors, err)
+	return err
+}
+
+// synchronize discards tokens until it reaches what is probably the start of
+// the next statement
+func (p *Parser) synchronize() {
+	p.advance()
+
+	for !p.isAtEnd() {
+		if p.previous()._type == SEMICOLON {
+			return
+		}
+
+		switch p.peek()._type {
+		case CLASS, FUN, VAR, CONST, FOR, IF, WHILE, PRINT, RETURN, THROW, TRY:
+			return
+		}
+
+		p.advance()
+	}
+}
@@ -0,0 +1,165 @@
+package lox
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// parse scans and parses source as a program, failing on scan errors
+func parse(t *testing.T, source string) ([]Stmt, []ParseError) {
+	t.Helper()
+	tokens, scanErrors := Tokenize(source)
+	if len(scanErrors) > 0 {
+		t.Fatalf("Tokenize(%q) errors: %v", source, scanErrors)
+	}
+	parser := NewParser(tokens)
+	return parser.Parse()
+}
+
+// expectParseErrors fails unless parsing source reports exactly want, each
+// as ParseError.Error formats it
+func expectParseErrors(t *testing.T, source string, want ...string) {
+	t.Helper()
+	_, errors := parse(t, source)
+	if len(errors) != len(want) {
+		t.Fatalf("Parse(%q) errors = %v, want %q", source, errors, want)
+	}
+	for i := range want {
+		if got := errors[i].Error(); got != want[i] {
+			t.Errorf("Parse(%q) error %d = %q, want %q", source, i, got, want[i])
+		}
+	}
+}
+
+func TestMissingLeftOperand(t *testing.T) {
+	for _, op := range []string{"+", "*", "/", "==", "!=", "<", "<=", ">", ">="} {
+		expectParseErrors(t, op+" 1;",
+			"[line 1] Error at '"+op+"': Binary operator '"+op+"' requires a left-hand operand.")
+	}
+}
+
+func TestMissingLeftOperandKeepsGoing(t *testing.T) {
+	// The right operand is parsed and dropped, so the next error is found
+	expectParseErrors(t, "print * 2 + 3;\nprint ;",
+		"[line 1] Error at '*': Binary operator '*' requires a left-hand operand.",
+		"[line 2] Error at ';': Expect expression.")
+}
+
+func TestRecoverFromErrors(t *testing.T) {
+	statements, _ := parse(t, "print 1;")
+	if len(statements) != 1 {
+		t.Fatalf("Parse found %d statements, want 1", len(statements))
+	}
+
+	expectParseErrors(t, "var = 1;\nprint 2;\nprint (3;\nprint 4;",
+		"[line 1] Error at '=': Expect variable name.",
+		"[line 3] Error at ';': Expect ')' after expression.")
+}
+
+func TestSynchronizeAtStatementKeywords(t *testing.T) {
+	// No semicolon before the second error, synchronize stops at the keyword
+	expectParseErrors(t, "var a = ) class B {}\nfun f( {}",
+		"[line 1] Error at ')': Expect expression.",
+		"[line 2] Error at '{': Expect parameter name.")
+}
+
+func TestParseErrorToken(t *testing.T) {
+	_, errors := parse(t, "print (1 + 2;")
+	if len(errors) != 1 {
+		t.Fatalf("Parse errors = %v, want 1", errors)
+	}
+	err := errors[0]
+	if err.Token._type != SEMICOLON || err.Token.lexeme != ";" || err.Message != "Expect ')' after expression." {
+		t.Errorf("Parse error = %+v, want one at ';' expecting ')'", err)
+	}
+
+	expectParseErrors(t, "print (1 + 2",
+		"[line 1] Error at end: Expect ')' after expression.")
+}
+
+func TestParseErrorExitCode(t *testing.T) {
+	expectError(t, "print (1;", ExitSyntaxError,
+		"[line 1] Error at ';': Expect ')' after expression.\n"+
+			"print (1;\n"+
+			"        ^\n")
+}
+
+func TestNestingDepth(t *testing.T) {
+	const limit = 8
+	tests := []struct{ name, open, close string }{
+		{"grouping", "(", ")"},
+		{"call", "f(", ")"},
+		{"array", "[", "]"},
+		{"map", `{"a": `, "}"},
+		{"unary", "- ", ""},
+		{"assignment", "a = ", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The print statement and the outermost expression are a level
+			// each, and each construct adds one
+			for nesting, ok := range map[int]bool{limit - 2: true, limit - 1: false} {
+				source := "print " + strings.Repeat(test.open, nesting) + "1" + strings.Repeat(test.close, nesting) + ";"
+				tokens, _ := Tokenize(source)
+				parser := NewParser(tokens)
+				parser.MaxDepth = limit
+				_, errors := parser.Parse()
+				if ok && len(errors) > 0 {
+					t.Errorf("Parse(%q) errors = %v, want none", source, errors)
+				}
+				if !ok && (len(errors) != 1 || !strings.HasSuffix(errors[0].Error(), "Expression too deeply nested (max 8).")) {
+					t.Errorf("Parse(%q) errors = %v, want too deeply nested", source, errors)
+				}
+			}
+		})
+	}
+}
+
+func TestPathologicalNesting(t *testing.T) {
+	const n = 100000
+	for _, source := range []string{
+		strings.Repeat("(", n) + "1" + strings.Repeat(")", n) + ";",
+		strings.Repeat("f(", n) + strings.Repeat(")", n) + ";",
+		strings.Repeat("[", n) + strings.Repeat("]", n) + ";",
+		"print " + strings.Repeat(`{"a":`, n) + "1" + strings.Repeat("}", n) + ";",
+		strings.Repeat("-", n) + "1;",
+		strings.Repeat("!", n) + "true;",
+		strings.Repeat("{", n) + strings.Repeat("}", n),
+		strings.Repeat("if (true) ", n) + "print 1;",
+		strings.Repeat("while (false) ", n) + "print 1;",
+		strings.Repeat("for (;false;) ", n) + "print 1;",
+	} {
+		_, stderr, code := run(t, source)
+		if code != ExitSyntaxError || !strings.Contains(stderr, "too deeply nested (max 1024).") {
+			t.Errorf("run(%.20q...) exited %d, reporting %.100q", source, code, stderr)
+		}
+	}
+	// Chained binary operators are parsed iteratively, so they don't nest
+	expectOutput(t, "print 1"+strings.Repeat(" + 1", n)+";", strconv.Itoa(n+1)+"\n")
+}
+
+func TestParserMaxErrors(t *testing.T) {
+	tokens, _ := Tokenize(strings.Repeat("var;", 500))
+	parser := NewParser(tokens)
+	if _, errors := parser.Parse(); len(errors) != DefaultMaxErrors+1 {
+		t.Errorf("Parse reported %d errors, want %d", len(errors), DefaultMaxErrors+1)
+	}
+
+	parser = NewParser(tokens)
+	parser.MaxErrors = 2
+	_, errors := parser.Parse()
+	want := []string{
+		"[line 1] Error at ';': Expect variable name.",
+		"[line 1] Error at ';': Expect variable name.",
+		"[line 1] Error at 'var': Too many errors.",
+	}
+	if len(errors) != len(want) {
+		t.Fatalf("Parse with MaxErrors 2 reported %v, want %q", errors, want)
+	}
+	for i := range want {
+		if errors[i].Error() != want[i] {
+			t.Errorf("Parse error %d = %q, want %q", i, errors[i].Error(), want[i])
+		}
+	}
+}
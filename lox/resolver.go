@@ -0,0 +1,553 @@
+package lox
+
+import (
+	"fmt"
+	"sort"
+)
+
+type functionType int
+
+const (
+	NONE functionType = iota
+	FUNCTION
+	METHOD
+	INITIALIZER
+)
+
+type classType int
+
+const (
+	NO_CLASS classType = iota
+	CLASS_BODY
+	SUBCLASS_BODY
+)
+
+// local is the resolver's bookkeeping for a declared local variable
+type local struct {
+	name    Token
+	defined bool // Whether its initializer has been resolved
+	used    bool // Whether it has been read
+	isConst bool // Whether it was declared with const
+}
+
+// Resolver walks the tree after parsing and tells the evaluator how many
+// scopes away each local variable reference was declared
+type Resolver struct {
+	evaluator       *Evaluator
+	scopes          []map[string]*local // Innermost scope last
+	currentFunction functionType
+	currentClass    classType
+	inStaticMethod  bool
+	loopDepth       int // Number of loops enclosing the current statement
+	switchDepth     int // Number of switches enclosing the current statement
+	hadError        bool
+
+	// initializingGlobal names the global whose initializer is being
+	// resolved, since globals aren't tracked in scopes
+	initializingGlobal string
+
+	// WarnUnused makes each scope warn, as it closes, about the locals in
+	// it that were never read
+	WarnUnused bool
+}
+
+func NewResolver(evaluator *Evaluator) *Resolver {
+	return &Resolver{
+		evaluator: evaluator,
+	}
+}
+
+// Resolve resolves a whole program. Errors are reported as they are found.
+func (r *Resolver) Resolve(statements []Stmt) {
+	r.resolveStmts(statements)
+}
+
+// warning reports a problem that doesn't stop the program to the
+// evaluator's Err
+func (r *Resolver) warning(line int, message string) {
+	fmt.Fprintf(r.evaluator.Err, "[line %d] Warning: %s\n", line, message)
+}
+
+// error reports a resolution error at tok to the evaluator's Err
+func (r *Resolver) error(tok Token, message string) {
+	report(r.evaluator.Err, tok.line, where(tok), message)
+	r.hadError = true
+}
+
+// resolveStmts resolves a list of statements, warning once if any follow a
+// return, break, continue or throw and so can never run
+func (r *Resolver) resolveStmts(statements []Stmt) {
+	warned := false
+	for i, stmt := range statements {
+		r.resolveStmt(stmt)
+		if keyword, ok := jumpKeyword(stmt); ok && !warned && i < len(statements)-1 {
+			r.warning(keyword.line, fmt.Sprintf("Code after '%s' is unreachable.", keyword.lexeme))
+			warned = true
+		}
+	}
+}
+
+// jumpKeyword returns the keyword of a statement that always jumps away
+func jumpKeyword(stmt Stmt) (Token, bool) {
+	switch stmt := stmt.(type) {
+	case *Return:
+		return stmt.Keyword, true
+	case *Break:
+		return stmt.Keyword, true
+	case *Continue:
+		return stmt.Keyword, true
+	case *Throw:
+		return stmt.Keyword, true
+	}
+	return Token{}, false
+}
+
+func (r *Resolver) resolveStmt(stmt Stmt) {
+	stmt.Accept(r)
+}
+
+func (r *Resolver) resolveExpr(expr Expr) {
+	expr.Accept(r)
+}
+
+func (r *Resolver) beginScope() {
+	r.scopes = append(r.scopes, make(map[string]*local))
+}
+
+func (r *Resolver) endScope() {
+	scope := r.scopes[len(r.scopes)-1]
+	r.scopes = r.scopes[:len(r.scopes)-1]
+
+	if r.WarnUnused {
+		r.warnUnused(scope)
+	}
+}
+
+// warnUnused warns about each variable in scope that was never read, in
+// source order
+func (r *Resolver) warnUnused(scope map[string]*local) {
+	var unused []Token
+	for _, variable := range scope {
+		if !variable.used {
+			unused = append(unused, variable.name)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		if unused[i].line != unused[j].line {
+			return unused[i].line < unused[j].line
+		}
+		return unused[i].lexeme < unused[j].lexeme
+	})
+	for _, name := range unused {
+		r.warning(name.line, "Local variable '"+name.lexeme+"' is never used.")
+	}
+}
+
+func (r *Resolver) declare(name Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	scope := r.scopes[len(r.scopes)-1]
+	if _, ok := scope[name.lexeme]; ok {
+		r.error(name, "Already a variable with this name in this scope.")
+	}
+	scope[name.lexeme] = &local{name: name}
+}
+
+func (r *Resolver) define(name Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	r.scopes[len(r.scopes)-1][name.lexeme].defined = true
+}
+
+// markUsed exempts a local that isn't a plain variable, like a parameter,
+// from the unused warning
+func (r *Resolver) markUsed(name Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	r.scopes[len(r.scopes)-1][name.lexeme].used = true
+}
+
+// resolveLocal records the distance to the innermost scope declaring name and
+// returns its bookkeeping. Names that aren't found are assumed to be globals.
+func (r *Resolver) resolveLocal(expr Expr, name Token) *local {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if variable, ok := r.scopes[i][name.lexeme]; ok {
+			r.evaluator.resolve(expr, len(r.scopes)-1-i)
+			return variable
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) resolveFunction(function *Function, kind functionType) {
+	enclosingFunction := r.currentFunction
+	r.currentFunction = kind
+
+	// Loops and switches outside the function can't be broken out of from
+	// inside it
+	enclosingLoopDepth, enclosingSwitchDepth := r.loopDepth, r.switchDepth
+	r.loopDepth, r.switchDepth = 0, 0
+
+	r.beginScope()
+	for _, param := range function.Params {
+		r.declare(param)
+		r.define(param)
+		r.markUsed(param)
+	}
+	r.resolveStmts(function.Body)
+	r.endScope()
+
+	r.currentFunction = enclosingFunction
+	r.loopDepth, r.switchDepth = enclosingLoopDepth, enclosingSwitchDepth
+}
+
+func (r *Resolver) VisitExpressionStmt(stmt *Expression) any {
+	r.resolveExpr(stmt.Expression)
+	return nil
+}
+
+func (r *Resolver) VisitPrintStmt(stmt *Print) any {
+	r.resolveExpr(stmt.Expression)
+	return nil
+}
+
+func (r *Resolver) VisitVarStmt(stmt *Var) any {
+	r.declare(stmt.Name)
+	if stmt.Initializer != nil {
+		if len(r.scopes) == 0 {
+			r.initializingGlobal = stmt.Name.lexeme
+		}
+		r.resolveExpr(stmt.Initializer)
+		r.initializingGlobal = ""
+	}
+	r.define(stmt.Name)
+	if stmt.Const && len(r.scopes) > 0 {
+		r.scopes[len(r.scopes)-1][stmt.Name.lexeme].isConst = true
+	}
+	return nil
+}
+
+func (r *Resolver) VisitBlockStmt(stmt *Block) any {
+	r.beginScope()
+	r.resolveStmts(stmt.Statements)
+	r.endScope()
+	return nil
+}
+
+func (r *Resolver) VisitIfStmt(stmt *If) any {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		r.resolveStmt(stmt.ElseBranch)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitWhileStmt(stmt *While) any {
+	r.resolveExpr(stmt.Condition)
+	r.loopDepth++
+	r.resolveStmt(stmt.Body)
+	r.loopDepth--
+	if stmt.Increment != nil {
+		r.resolveExpr(stmt.Increment)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitContinueStmt(stmt *Continue) any {
+	if r.loopDepth == 0 {
+		r.error(stmt.Keyword, "Must be inside a loop to use 'continue'.")
+	}
+	return nil
+}
+
+func (r *Resolver) VisitBreakStmt(stmt *Break) any {
+	if r.loopDepth == 0 && r.switchDepth == 0 {
+		r.error(stmt.Keyword, "Must be inside a loop or switch to use 'break'.")
+	}
+	return nil
+}
+
+// VisitSwitchStmt gives each arm its own scope, like a block
+func (r *Resolver) VisitSwitchStmt(stmt *Switch) any {
+	r.resolveExpr(stmt.Subject)
+	r.switchDepth++
+	defer func() { r.switchDepth-- }()
+	for _, arm := range stmt.Cases {
+		r.resolveExpr(arm.Value)
+		r.beginScope()
+		r.resolveStmts(arm.Body)
+		r.endScope()
+	}
+	if stmt.Default != nil {
+		r.beginScope()
+		r.resolveStmts(stmt.Default)
+		r.endScope()
+	}
+	return nil
+}
+
+// VisitForEachStmt declares the loop variable in a scope of its own
+func (r *Resolver) VisitForEachStmt(stmt *ForEach) any {
+	r.resolveExpr(stmt.Collection)
+	r.beginScope()
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.loopDepth++
+	r.resolveStmt(stmt.Body)
+	r.loopDepth--
+	r.endScope()
+	return nil
+}
+
+func (r *Resolver) VisitThrowStmt(stmt *Throw) any {
+	r.resolveExpr(stmt.Value)
+	return nil
+}
+
+// VisitTryStmt resolves each clause in its own scope, the catch clause's
+// holding the caught value
+func (r *Resolver) VisitTryStmt(stmt *Try) any {
+	r.beginScope()
+	r.resolveStmts(stmt.Body)
+	r.endScope()
+	if stmt.Catch != nil {
+		r.beginScope()
+		r.declare(stmt.CatchName)
+		r.define(stmt.CatchName)
+		r.markUsed(stmt.CatchName)
+		r.resolveStmts(stmt.Catch)
+		r.endScope()
+	}
+	if stmt.Finally != nil {
+		r.beginScope()
+		r.resolveStmts(stmt.Finally)
+		r.endScope()
+	}
+	return nil
+}
+
+func (r *Resolver) VisitFunctionStmt(stmt *Function) any {
+	// Define eagerly so the function can refer to itself
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.markUsed(stmt.Name)
+	r.resolveFunction(stmt, FUNCTION)
+	return nil
+}
+
+func (r *Resolver) VisitClassStmt(stmt *Class) any {
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.markUsed(stmt.Name)
+
+	enclosingClass := r.currentClass
+	r.currentClass = CLASS_BODY
+
+	if stmt.Superclass != nil {
+		if stmt.Superclass.Name.lexeme == stmt.Name.lexeme {
+			r.error(stmt.Superclass.Name, "A class can't inherit from itself.")
+		}
+		r.currentClass = SUBCLASS_BODY
+		r.resolveExpr(stmt.Superclass)
+	}
+
+	// Static methods are resolved outside the scopes for "this" and "super"
+	enclosingStatic := r.inStaticMethod
+	r.inStaticMethod = true
+	for _, method := range stmt.StaticMethods {
+		r.resolveFunction(method, METHOD)
+	}
+	r.inStaticMethod = false
+
+	if stmt.Superclass != nil {
+		r.beginScope()
+		super := Token{_type: SUPER, lexeme: "super", line: stmt.Name.line}
+		r.scopes[len(r.scopes)-1]["super"] = &local{name: super, defined: true, used: true}
+	}
+
+	// Methods close over a scope that defines "this"
+	r.beginScope()
+	this := Token{_type: THIS, lexeme: "this", line: stmt.Name.line}
+	r.scopes[len(r.scopes)-1]["this"] = &local{name: this, defined: true, used: true}
+
+	for _, method := range stmt.Methods {
+		kind := METHOD
+		if method.Name.lexeme == "init" {
+			kind = INITIALIZER
+		}
+		r.resolveFunction(method, kind)
+	}
+
+	r.endScope()
+	if stmt.Superclass != nil {
+		r.endScope()
+	}
+
+	r.currentClass = enclosingClass
+	r.inStaticMethod = enclosingStatic
+	return nil
+}
+
+func (r *Resolver) VisitReturnStmt(stmt *Return) any {
+	if r.currentFunction == NONE {
+		r.error(stmt.Keyword, "Can't return from top-level code.")
+	}
+	if stmt.Value != nil {
+		if r.currentFunction == INITIALIZER {
+			r.error(stmt.Keyword, "Can't return a value from an initializer.")
+		}
+		r.resolveExpr(stmt.Value)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitLiteralExpr(literal *Literal) any {
+	return nil
+}
+
+func (r *Resolver) VisitBinaryExpr(binary *Binary) any {
+	r.resolveExpr(binary.Left)
+	r.resolveExpr(binary.Right)
+	return nil
+}
+
+func (r *Resolver) VisitInterpolationExpr(interpolation *Interpolation) any {
+	for _, part := range interpolation.Parts {
+		r.resolveExpr(part)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitGroupingExpr(grouping *Grouping) any {
+	r.resolveExpr(grouping.Expression)
+	return nil
+}
+
+func (r *Resolver) VisitUnaryExpr(unary *Unary) any {
+	r.resolveExpr(unary.Right)
+	return nil
+}
+
+func (r *Resolver) VisitLogicalExpr(logical *Logical) any {
+	r.resolveExpr(logical.Left)
+	r.resolveExpr(logical.Right)
+	return nil
+}
+
+func (r *Resolver) VisitConditionalExpr(conditional *Conditional) any {
+	r.resolveExpr(conditional.Condition)
+	r.resolveExpr(conditional.ThenBranch)
+	r.resolveExpr(conditional.ElseBranch)
+	return nil
+}
+
+func (r *Resolver) VisitGetExpr(get *Get) any {
+	r.resolveExpr(get.Object)
+	return nil
+}
+
+func (r *Resolver) VisitSetExpr(set *Set) any {
+	r.resolveExpr(set.Value)
+	r.resolveExpr(set.Object)
+	return nil
+}
+
+func (r *Resolver) VisitArrayLiteralExpr(array *ArrayLiteral) any {
+	for _, element := range array.Elements {
+		r.resolveExpr(element)
+	}
+	return nil
+}
+
+func (r *Resolver) VisitMapLiteralExpr(literal *MapLiteral) any {
+	for i, key := range literal.Keys {
+		r.resolveExpr(key)
+		r.resolveExpr(literal.Values[i])
+	}
+	return nil
+}
+
+func (r *Resolver) VisitIndexExpr(index *Index) any {
+	r.resolveExpr(index.Object)
+	r.resolveExpr(index.Index)
+	return nil
+}
+
+func (r *Resolver) VisitIndexAssignExpr(assign *IndexAssign) any {
+	r.resolveExpr(assign.Value)
+	r.resolveExpr(assign.Object)
+	r.resolveExpr(assign.Index)
+	return nil
+}
+
+func (r *Resolver) VisitSuperExpr(super *Super) any {
+	if r.inStaticMethod {
+		r.error(super.Keyword, "Can't use 'super' in a static method.")
+		return nil
+	}
+	if r.currentClass == NO_CLASS {
+		r.error(super.Keyword, "Can't use 'super' outside of a class.")
+		return nil
+	}
+	if r.currentClass != SUBCLASS_BODY {
+		r.error(super.Keyword, "Can't use 'super' in a class with no superclass.")
+		return nil
+	}
+	r.resolveLocal(super, super.Keyword)
+	return nil
+}
+
+func (r *Resolver) VisitThisExpr(this *This) any {
+	if r.inStaticMethod {
+		r.error(this.Keyword, "Can't use 'this' in a static method.")
+		return nil
+	}
+	if r.currentClass == NO_CLASS {
+		r.error(this.Keyword, "Can't use 'this' outside of a class.")
+		return nil
+	}
+	r.resolveLocal(this, this.Keyword)
+	return nil
+}
+
+func (r *Resolver) VisitFunctionExpr(function *FunctionExpr) any {
+	r.resolveFunction(function.Declaration, FUNCTION)
+	return nil
+}
+
+// VisitVariableExpr rejects reading a local in its own initializer. Globals
+// aren't tracked, so as in jlox "var a = a;" at the top level is allowed:
+// it copies an earlier global a, or fails at run time if there isn't one.
+func (r *Resolver) VisitVariableExpr(variable *Variable) any {
+	if len(r.scopes) > 0 {
+		if declared, ok := r.scopes[len(r.scopes)-1][variable.Name.lexeme]; ok && !declared.defined {
+			r.error(variable.Name, "Can't read local variable in its own initializer.")
+		}
+	} else if variable.Name.lexeme == r.initializingGlobal {
+		r.error(variable.Name, "Can't read global variable in its own initializer.")
+	}
+	if declared := r.resolveLocal(variable, variable.Name); declared != nil {
+		declared.used = true
+	}
+	return nil
+}
+
+func (r *Resolver) VisitAssignExpr(assign *Assign) any {
+	r.resolveExpr(assign.Value)
+	if variable := r.resolveLocal(assign, assign.Name); variable != nil && variable.isConst {
+		r.error(assign.Name, fmt.Sprintf("Cannot assign to constant '%s'.", assign.Name.lexeme))
+	}
+	return nil
+}
+
+func (r *Resolver) VisitCallExpr(call *Call) any {
+	r.resolveExpr(call.Callee)
+	for _, argument := range call.Arguments {
+		r.resolveExpr(argument)
+	}
+	return nil
+}
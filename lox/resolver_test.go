@@ -0,0 +1,125 @@
+package lox
+
+import "testing"
+
+func TestResolveClosureScope(t *testing.T) {
+	// Without resolution the second call would see the block's a
+	expectOutput(t, `
+		var a = "global";
+		{
+			fun showA() { print a; }
+			showA();
+			var a = "block";
+			showA();
+		}`, "global\nglobal\n")
+}
+
+func TestResolveOwnInitializer(t *testing.T) {
+	expectError(t, "{ var a = 1; { var a = a; } }", ExitSyntaxError,
+		"[line 1] Error at 'a': Can't read local variable in its own initializer.\n")
+}
+
+func TestResolveOwnInitializerGlobal(t *testing.T) {
+	// Even when an earlier declaration would give it a value
+	expectError(t, "var a = 1; var a = a + 1; print a;", ExitSyntaxError,
+		"[line 1] Error at 'a': Can't read global variable in its own initializer.\n")
+	expectError(t, "var b = b;", ExitSyntaxError,
+		"[line 1] Error at 'b': Can't read global variable in its own initializer.\n")
+	// A function body runs later, once the global is defined
+	expectOutput(t, "var f = fun (n) { if (n > 0) return f(n - 1); return n; }; print f(3);", "0\n")
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct{ source, want string }{
+		{"return 1;", "Error at 'return': Can't return from top-level code."},
+		{"print this;", "Error at 'this': Can't use 'this' outside of a class."},
+		{"fun f() { return this; }", "Error at 'this': Can't use 'this' outside of a class."},
+		{"class A { init() { return 1; } }", "Error at 'return': Can't return a value from an initializer."},
+		{"{ var a; var a; }", "Error at 'a': Already a variable with this name in this scope."},
+		{"fun f(a, a) {}", "Error at 'a': Already a variable with this name in this scope."},
+	}
+	for _, test := range tests {
+		// The program would print if it ran
+		expectError(t, `print "ran"; `+test.source, ExitSyntaxError, "[line 1] "+test.want+"\n")
+	}
+}
+
+func TestResolveAllowed(t *testing.T) {
+	expectOutput(t, `
+		var a = 1;
+		var a = 2;
+		class A { init() { return; } }
+		{ var b = 1; { var b = 2; print b; } }
+		print a;`, "2\n2\n")
+}
+
+func TestWarnUnused(t *testing.T) {
+	// Parameters and globals are never reported
+	source := "{ var used = 1; var unused = 2; print used; } var global; fun f(param) {}"
+	_, stderr, _ := run(t, source)
+	if stderr != "" {
+		t.Errorf("warned without WarnUnused:\n%s", stderr)
+	}
+
+	interpreter := NewInterpreter()
+	interpreter.WarnUnused = true
+	stdout, stderr, code := runWith(t, interpreter, source)
+	if code != ExitOK || stdout != "1\n" {
+		t.Errorf("with WarnUnused, program exited %d printing %q", code, stdout)
+	}
+	if want := "[line 1] Warning: Local variable 'unused' is never used.\n"; stderr != want {
+		t.Errorf("with WarnUnused, reported:\n%s\nwant:\n%s", stderr, want)
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	source := `
+		fun f() {
+			print "reached";
+			return 1;
+			print "unreachable";
+		}
+		print f();`
+	stdout, stderr, code := run(t, source)
+	if code != ExitOK || stdout != "reached\n1\n" {
+		t.Errorf("program exited %d printing %q", code, stdout)
+	}
+	if want := "[line 4] Warning: Code after 'return' is unreachable.\n"; stderr != want {
+		t.Errorf("reported:\n%s\nwant:\n%s", stderr, want)
+	}
+
+	// A return ending its block is fine
+	expectOutput(t, `
+		fun g(n) {
+			if (n > 0) { return "positive"; }
+			return "other";
+		}
+		print g(1);`, "positive\n")
+}
+
+func TestConst(t *testing.T) {
+	for _, source := range []string{
+		"{ const x = 1; { x = 2; } }",
+		"{ const x = 1; x += 2; }",
+		"{ const x = 1; ++x; }",
+		"{ const x = 1; fun f() { x = 2; } }",
+	} {
+		expectError(t, `print "ran"; `+source, ExitSyntaxError,
+			"[line 1] Error at 'x': Cannot assign to constant 'x'.\n")
+	}
+	for _, source := range []string{"const g = 1; g = 2;", "const g = 1; --g;"} {
+		expectError(t, source, ExitRuntimeError, "Cannot assign to constant 'g'.\n[line 1] in script\n")
+	}
+
+	// Closures can read a const, and an inner scope can shadow it
+	expectOutput(t, `
+		{
+			const x = 1;
+			fun f() { return x; }
+			print f();
+			{ var x = 2; x = 3; print x; }
+			{ const x = 4; print x; }
+		}`, "1\n3\n4\n")
+
+	expectParseErrors(t, "const x;", "[line 1] Error at ';': Expect '=' after constant name.")
+}
@@ -0,0 +1,494 @@
+package lox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ScanError is a lexical error found while scanning
+type ScanError struct {
+	Line    int
+	Offset  int // Byte offset in the source of the text at fault
+	Message string
+}
+
+func (err ScanError) Error() string {
+	return fmt.Sprintf("[line %d] Error: %s", err.Line, err.Message)
+}
+
+type Scanner struct {
+	source  string
+	tokens  []Token
+	start   int
+	current int
+	line    int
+
+	// column is the column of the next rune, startColumn that of the
+	// token being scanned.
+	column      int
+	startColumn int
+
+	// Brace depth for each "${" we are currently inside of, innermost last.
+	interpolations []int
+
+	// KeepComments makes the scanner emit each comment as a COMMENT token
+	// instead of dropping it, for tools that need the original text. The
+	// parser doesn't accept COMMENT tokens.
+	KeepComments bool
+
+	// MaxErrors is how many errors to report before giving up on the rest
+	// of the source, 0 for no limit
+	MaxErrors int
+
+	errors []ScanError
+}
+
+func NewScanner(source string) Scanner {
+	return Scanner{
+		source:    source,
+		line:      1,
+		column:    1,
+		MaxErrors: DefaultMaxErrors,
+	}
+}
+
+func (scan *Scanner) isAtEnd() bool {
+	return scan.current >= len(scan.source)
+}
+
+func (scan *Scanner) ScanTokens() []Token {
+	// A "#!" line at the very start lets scripts be run directly
+	if strings.HasPrefix(scan.source, "#!") {
+		for scan.peek() != '\n' && !scan.isAtEnd() {
+			scan.advance()
+		}
+	}
+	for !scan.isAtEnd() {
+		if tooManyErrors(len(scan.errors), scan.MaxErrors) {
+			scan.start = scan.current
+			scan.addError("Too many errors.")
+			break
+		}
+		scan.start = scan.current
+		scan.startColumn = scan.column
+		scan.scanToken()
+	}
+	if len(scan.interpolations) > 0 {
+		scan.addError("Unterminated string.")
+	}
+	tok := Token{
+		_type:   EOF,
+		lexeme:  "",
+		literal: LoxEmptyLiteral{},
+		line:    scan.line,
+		column:  scan.column,
+
+		startOffset: scan.current,
+		endOffset:   scan.current,
+	}
+	scan.tokens = append(scan.tokens, tok)
+	return scan.tokens
+}
+
+func (scan *Scanner) scanToken() {
+	var char rune = scan.advance()
+	switch char {
+	case '(':
+		scan.addToken(LEFT_PAREN)
+	case ')':
+		scan.addToken(RIGHT_PAREN)
+	case '{':
+		if depth := len(scan.interpolations); depth > 0 {
+			scan.interpolations[depth-1]++
+		}
+		scan.addToken(LEFT_BRACE)
+	case '[':
+		scan.addToken(LEFT_BRACKET)
+	case ']':
+		scan.addToken(RIGHT_BRACKET)
+	case '}':
+		depth := len(scan.interpolations)
+		if depth > 0 && scan.interpolations[depth-1] == 0 {
+			// This closes a "${", so pick the string back up
+			scan.interpolations = scan.interpolations[:depth-1]
+			scan.parseString()
+			break
+		}
+		if depth > 0 {
+			scan.interpolations[depth-1]--
+		}
+		scan.addToken(RIGHT_BRACE)
+	case ',':
+		scan.addToken(COMMA)
+	case '.':
+		scan.addToken(DOT)
+	case '-':
+		if scan.match('=') {
+			scan.addToken(MINUS_EQUAL)
+		} else if scan.match('-') {
+			scan.addToken(MINUS_MINUS)
+		} else {
+			scan.addToken(MINUS)
+		}
+	case '+':
+		if scan.match('=') {
+			scan.addToken(PLUS_EQUAL)
+		} else if scan.match('+') {
+			scan.addToken(PLUS_PLUS)
+		} else {
+			scan.addToken(PLUS)
+		}
+	case ';':
+		scan.addToken(SEMICOLON)
+	case '*':
+		if scan.match('*') {
+			scan.addToken(STAR_STAR)
+		} else if scan.match('=') {
+			scan.addToken(STAR_EQUAL)
+		} else {
+			scan.addToken(STAR)
+		}
+	case '?':
+		scan.addToken(QUESTION)
+	case ':':
+		scan.addToken(COLON)
+	case '!':
+		if scan.match('=') {
+			scan.addToken(BANG_EQUAL)
+		} else {
+			scan.addToken(BANG)
+		}
+	case '=':
+		if scan.match('=') {
+			scan.addToken(EQUAL_EQUAL)
+		} else {
+			scan.addToken(EQUAL)
+		}
+	case '&':
+		scan.addToken(AMPERSAND)
+	case '|':
+		scan.addToken(PIPE)
+	case '^':
+		scan.addToken(CARET)
+	case '<':
+		if scan.match('=') {
+			scan.addToken(LESS_EQUAL)
+		} else if scan.match('<') {
+			scan.addToken(LESS_LESS)
+		} else {
+			scan.addToken(LESS)
+		}
+	case '>':
+		if scan.match('=') {
+			scan.addToken(GREATER_EQUAL)
+		} else if scan.match('>') {
+			scan.addToken(GREATER_GREATER)
+		} else {
+			scan.addToken(GREATER)
+		}
+	case '/':
+		if scan.match('/') {
+			for scan.peek() != '\n' && !scan.isAtEnd() {
+				scan.advance()
+			}
+			if scan.KeepComments {
+				scan.addToken(COMMENT)
+			}
+		} else if scan.match('*') {
+			scan.blockComment()
+		} else if scan.match('=') {
+			scan.addToken(SLASH_EQUAL)
+		} else {
+			scan.addToken(SLASH)
+		}
+	case ' ', '\r', '\t':
+		// Do nothing, skip
+	case '\n':
+		scan.line++
+	case '"':
+		scan.parseString()
+	default:
+		if scan.isDigit(char) {
+			scan.number()
+		} else if scan.isAlpha(char) {
+			scan.identifier()
+		} else {
+			message := fmt.Sprintf("Unexpected character: %c", char)
+			scan.addError(message)
+		}
+	}
+}
+
+// blockComment skips a /* */ comment, whose opening has been consumed.
+// Comments don't nest, the first */ closes it.
+func (scan *Scanner) blockComment() {
+	line := scan.line
+	for !strings.HasPrefix(scan.source[scan.current:], "*/") {
+		if scan.isAtEnd() {
+			scan.addError("Unterminated comment.")
+			return
+		}
+		if scan.advance() == '\n' {
+			scan.line++
+		}
+	}
+	scan.advance()
+	scan.advance()
+	if scan.KeepComments {
+		scan.addToken(COMMENT)
+		// Place it where it starts, like the comments the formatter lines
+		// code up with
+		scan.tokens[len(scan.tokens)-1].line = line
+	}
+}
+
+func (scan *Scanner) addError(message string) {
+	scan.errors = append(scan.errors, ScanError{Line: scan.line, Offset: scan.start, Message: message})
+}
+
+// advance consumes the next rune. Offsets stay in bytes so the source can
+// still be sliced directly for lexemes.
+func (scan *Scanner) advance() rune {
+	ret, size := utf8.DecodeRuneInString(scan.source[scan.current:])
+	scan.current += size
+	if ret == '\n' {
+		scan.column = 1
+	} else {
+		scan.column++
+	}
+	return ret
+
+}
+
+func (scan *Scanner) peek() rune {
+	if scan.isAtEnd() {
+		return '\000' // Rune literals are three-digit octals
+	}
+
+	ret, _ := utf8.DecodeRuneInString(scan.source[scan.current:])
+	return ret
+}
+
+func (scan *Scanner) peekNext() rune {
+	if scan.isAtEnd() {
+		return '\000'
+	}
+	_, size := utf8.DecodeRuneInString(scan.source[scan.current:])
+	if scan.current+size >= len(scan.source) {
+		return '\000'
+	}
+	ret, _ := utf8.DecodeRuneInString(scan.source[scan.current+size:])
+	return ret
+}
+
+func (scan *Scanner) match(expected rune) bool {
+	if scan.isAtEnd() {
+		return false
+	}
+
+	if scan.peek() != expected {
+		return false
+	}
+
+	scan.advance()
+
+	return true
+}
+
+// number scans a number literal: decimal digits with an optional fraction
+// and exponent, like 2.5e-3, or hex digits after "0x", like 0xFF
+func (scan *Scanner) number() {
+	if scan.source[scan.start] == '0' && (scan.peek() == 'x' || scan.peek() == 'X') && isHexDigit(scan.peekNext()) {
+		scan.hexNumber()
+		return
+	}
+
+	for scan.isDigit(scan.peek()) {
+		scan.advance()
+	}
+
+	if scan.peek() == '.' && scan.isDigit(scan.peekNext()) {
+		// Consume the "."
+		scan.advance()
+
+		for scan.isDigit(scan.peek()) {
+			scan.advance()
+		}
+	}
+
+	if scan.exponentFollows() {
+		// Consume the "e" and any sign
+		scan.advance()
+		if scan.peek() == '+' || scan.peek() == '-' {
+			scan.advance()
+		}
+		for scan.isDigit(scan.peek()) {
+			scan.advance()
+		}
+	}
+	number, err := strconv.ParseFloat(scan.source[scan.start:scan.current], 64)
+	if err != nil {
+		// Only out of range, like 1e999
+		scan.addError("Number literal is out of range.")
+		return
+	}
+	numberLiteral := LoxNumber{value: number}
+	scan.addTokenAndLiteral(NUMBER, numberLiteral)
+
+}
+
+// exponentFollows reports whether the scanner is at an exponent, an "e"
+// followed by digits with an optional sign
+func (scan *Scanner) exponentFollows() bool {
+	rest := scan.source[scan.current:]
+	if !strings.HasPrefix(rest, "e") && !strings.HasPrefix(rest, "E") {
+		return false
+	}
+	rest = rest[1:]
+	if strings.HasPrefix(rest, "+") || strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	}
+	return rest != "" && rest[0] >= '0' && rest[0] <= '9'
+}
+
+// hexNumber scans the rest of a hex literal after the leading "0"
+func (scan *Scanner) hexNumber() {
+	// Consume the "x"
+	scan.advance()
+
+	var number float64
+	for isHexDigit(scan.peek()) {
+		digit, _ := strconv.ParseUint(string(scan.advance()), 16, 8)
+		number = number*16 + float64(digit)
+	}
+	scan.addTokenAndLiteral(NUMBER, LoxNumber{value: number})
+}
+
+func isHexDigit(char rune) bool {
+	return (char >= '0' && char <= '9') || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F')
+}
+
+func (scan *Scanner) identifier() {
+	for scan.isAlphaNumeric(scan.peek()) {
+		scan.advance()
+	}
+
+	text := scan.source[scan.start:scan.current]
+	_type, ok := keywords[text]
+	if !ok {
+		_type = IDENTIFIER
+	}
+	scan.addToken(_type)
+}
+
+func (scan *Scanner) isDigit(char rune) bool {
+	return char >= '0' && char <= '9'
+}
+
+func (scan *Scanner) isAlpha(char rune) bool {
+	return (char >= 'a' && char <= 'z') ||
+		(char >= 'A' && char <= 'Z') ||
+		char == '_' ||
+		(char >= utf8.RuneSelf && unicode.IsLetter(char))
+}
+
+func (scan *Scanner) isAlphaNumeric(char rune) bool {
+	return scan.isAlpha(char) || scan.isDigit(char)
+}
+
+// parseString scans the rest of a string literal. A "${" ends the current
+// segment with an INTERPOLATION token; the matching "}" resumes the string.
+// A backslash at the end of a line continues the string on the next line
+// without a newline in its value.
+func (scan *Scanner) parseString() {
+	var value strings.Builder
+	for scan.peek() != '"' && !scan.isAtEnd() {
+		rest := scan.source[scan.current:]
+		if strings.HasPrefix(rest, "\\${") {
+			// Escaped, keep the "${" as-is
+			scan.advance()
+			value.WriteRune(scan.advance())
+			value.WriteRune(scan.advance())
+			continue
+		}
+		if strings.HasPrefix(rest, "\\\n") || strings.HasPrefix(rest, "\\\r\n") {
+			// Line continuation, drop the backslash and line break
+			scan.advance()
+			if scan.peek() == '\r' {
+				scan.advance()
+			}
+			scan.advance()
+			scan.line++
+			continue
+		}
+		if strings.HasPrefix(rest, "${") {
+			scan.advance()
+			scan.advance()
+			scan.interpolations = append(scan.interpolations, 0)
+			scan.addTokenAndLiteral(INTERPOLATION, LoxString{value: value.String()})
+			return
+		}
+		if scan.peek() == '\n' {
+			scan.line++
+		}
+		value.WriteRune(scan.advance())
+	}
+
+	if scan.isAtEnd() {
+		// Any "${" still open is part of this string, don't report it again
+		scan.interpolations = nil
+		scan.addError("Unterminated string.")
+		return
+	}
+
+	// The closing "
+	scan.advance()
+
+	literal := LoxString{
+		value: value.String(),
+	}
+	scan.addTokenAndLiteral(STRING, literal)
+}
+
+func (scan *Scanner) addToken(_type TokenType) {
+	scan.addTokenAndLiteral(_type, LoxEmptyLiteral{})
+}
+
+func (scan *Scanner) addTokenAndLiteral(_type TokenType, literal LoxLiteral) {
+	text := scan.source[scan.start:scan.current]
+	tok := Token{
+		_type:   _type,
+		lexeme:  text,
+		literal: literal,
+		line:    scan.line,
+		column:  scan.startColumn,
+
+		startOffset: scan.start,
+		endOffset:   scan.current,
+	}
+	scan.tokens = append(scan.tokens, tok)
+}
+
+// TokenizeWithComments is Tokenize, keeping comments as COMMENT tokens
+func TokenizeWithComments(source string) ([]Token, []ScanError) {
+	return tokenize(source, true, DefaultMaxErrors)
+}
+
+// Tokenize scans source into tokens, returning any errors found along the
+// way instead of reporting them.
+func Tokenize(source string) ([]Token, []ScanError) {
+	return tokenize(source, false, DefaultMaxErrors)
+}
+
+// tokenize is Tokenize, keeping comments if keepComments is set and giving
+// up after maxErrors errors
+func tokenize(source string, keepComments bool, maxErrors int) ([]Token, []ScanError) {
+	scanner := NewScanner(source)
+	scanner.KeepComments = keepComments
+	scanner.MaxErrors = maxErrors
+	tokens := scanner.ScanTokens()
+	return tokens, scanner.errors
+}
@@ -0,0 +1,335 @@
+package lox
+
+import (
+	"strings"
+	"testing"
+)
+
+// tokenStrings scans source, failing on any error, and returns its tokens
+// as the tokenize command prints them
+func tokenStrings(t *testing.T, source string) []string {
+	t.Helper()
+	tokens, errors := Tokenize(source)
+	if len(errors) > 0 {
+		t.Fatalf("Tokenize(%q) errors: %v", source, errors)
+	}
+	var strs []string
+	for _, tok := range tokens {
+		strs = append(strs, tok.String())
+	}
+	return strs
+}
+
+func expectTokens(t *testing.T, source string, want ...string) {
+	t.Helper()
+	got := tokenStrings(t, source)
+	if len(got) != len(want) {
+		t.Fatalf("Tokenize(%q) = %q, want %q", source, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Tokenize(%q) token %d = %q, want %q", source, i, got[i], want[i])
+		}
+	}
+}
+
+func TestScanUnicodeIdentifiers(t *testing.T) {
+	expectTokens(t, "var café = naïve;",
+		"VAR var null",
+		"IDENTIFIER café null",
+		"EQUAL = null",
+		"IDENTIFIER naïve null",
+		"SEMICOLON ; null",
+		"EOF  null")
+}
+
+func TestScanMultibyteStrings(t *testing.T) {
+	expectTokens(t, "\"🎉 déjà\"\n\"ok\"",
+		"STRING \"🎉 déjà\" 🎉 déjà",
+		"STRING \"ok\" ok",
+		"EOF  null")
+
+	tokens, _ := Tokenize("\"🎉\"\nfoo")
+	if line := tokens[1].line; line != 2 {
+		t.Errorf("identifier after an emoji string is on line %d, want 2", line)
+	}
+}
+
+func TestScanUnicodeNonLetter(t *testing.T) {
+	_, errors := Tokenize("var a = 1 ✓ 2;")
+	if len(errors) != 1 || errors[0].Message != "Unexpected character: ✓" {
+		t.Errorf("Tokenize errors = %v, want one for ✓", errors)
+	}
+}
+
+func TestRunUnicode(t *testing.T) {
+	expectOutput(t, `var café = "☕"; print café + "🎉";`, "☕🎉\n")
+}
+
+func TestScanInterpolation(t *testing.T) {
+	expectTokens(t, `"a ${x} b"`,
+		`INTERPOLATION "a ${ a `,
+		"IDENTIFIER x null",
+		`STRING } b"  b`,
+		"EOF  null")
+}
+
+func TestScanEscapedInterpolation(t *testing.T) {
+	expectTokens(t, `"cost: \${x}"`, `STRING "cost: \${x}" cost: ${x}`, "EOF  null")
+}
+
+func TestScanUnterminatedInterpolation(t *testing.T) {
+	for _, source := range []string{`"a ${ "b }`, `"a ${1`, `"a ${1} b`} {
+		_, errors := Tokenize(source)
+		if len(errors) != 1 || errors[0].Message != "Unterminated string." {
+			t.Errorf("Tokenize(%q) errors = %v, want one unterminated string", source, errors)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{"", []string{"EOF  null"}},
+		{"(){};", []string{"LEFT_PAREN ( null", "RIGHT_PAREN ) null", "LEFT_BRACE { null",
+			"RIGHT_BRACE } null", "SEMICOLON ; null", "EOF  null"}},
+		{"a != 12 // note", []string{"IDENTIFIER a null", "BANG_EQUAL != null", "NUMBER 12 12.0", "EOF  null"}},
+		{"while whiles", []string{"WHILE while null", "IDENTIFIER whiles null", "EOF  null"}},
+	}
+	for _, test := range tests {
+		expectTokens(t, test.source, test.want...)
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	// The lexeme keeps the original form, the literal is always decimal
+	expectTokens(t, "0xFF 0x1f 0x0 1e3 1.5e3 2.5E-2 12e+1 1e21 100",
+		"NUMBER 0xFF 255.0", "NUMBER 0x1f 31.0", "NUMBER 0x0 0.0",
+		"NUMBER 1e3 1000.0", "NUMBER 1.5e3 1500.0", "NUMBER 2.5E-2 0.025",
+		"NUMBER 12e+1 120.0", "NUMBER 1e21 1000000000000000000000.0",
+		"NUMBER 100 100.0", "EOF  null")
+	expectOutput(t, "print 0xFF; print 2.5E-2; print 1e21;", "255\n0.025\n1000000000000000000000\n")
+}
+
+func TestBlockComments(t *testing.T) {
+	expectTokens(t, "a /* one\n// two */ b/**/c /* * / */",
+		"IDENTIFIER a null", "IDENTIFIER b null", "IDENTIFIER c null", "EOF  null")
+	// They don't nest
+	expectTokens(t, "/* /* */ a */", "IDENTIFIER a null", "STAR * null", "SLASH / null", "EOF  null")
+	expectOutput(t, "print 1; /* skipped\nprint 2; */ print 3;", "1\n3\n")
+
+	tokens, errors := Tokenize("a\n/* open\n")
+	if want := (ScanError{Line: 3, Offset: 2, Message: "Unterminated comment."}); len(errors) != 1 || errors[0] != want {
+		t.Errorf("Tokenize errors = %v, want %+v", errors, want)
+	}
+	if len(tokens) != 2 || tokens[1].Type() != EOF {
+		t.Errorf("Tokenize gave %d tokens, want a then EOF", len(tokens))
+	}
+}
+
+func TestKeepComments(t *testing.T) {
+	source := "a // line\n/* block\n */ b"
+	tokens, _ := Tokenize(source)
+	for _, tok := range tokens {
+		if tok.Type() == COMMENT {
+			t.Errorf("Tokenize gave comment %q", tok.lexeme)
+		}
+	}
+
+	tokens, errors := TokenizeWithComments(source)
+	if len(errors) > 0 {
+		t.Fatalf("TokenizeWithComments errors: %v", errors)
+	}
+	want := []struct {
+		_type  TokenType
+		lexeme string
+		line   int
+	}{
+		{IDENTIFIER, "a", 1}, {COMMENT, "// line", 1}, {COMMENT, "/* block\n */", 2},
+		{IDENTIFIER, "b", 3}, {EOF, "", 3},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("TokenizeWithComments gave %d tokens, want %d", len(tokens), len(want))
+	}
+	for i, w := range want {
+		if tok := tokens[i]; tok._type != w._type || tok.lexeme != w.lexeme || tok.line != w.line {
+			t.Errorf("token %d = %v %q on line %d, want %v %q on line %d",
+				i, tok._type, tok.lexeme, tok.line, w._type, w.lexeme, w.line)
+		}
+	}
+}
+
+func TestStringLineContinuation(t *testing.T) {
+	for _, source := range []string{"\"abc\\\ndef\" x", "\"abc\\\r\ndef\" x"} {
+		tokens, errors := Tokenize(source)
+		if len(errors) > 0 {
+			t.Fatalf("Tokenize(%q) errors: %v", source, errors)
+		}
+		if got := tokens[0].literal; got != (LoxString{value: "abcdef"}) {
+			t.Errorf("Tokenize(%q) string = %v, want abcdef", source, got)
+		}
+		if tokens[1].line != 2 {
+			t.Errorf("Tokenize(%q) token after the string on line %d, want 2", source, tokens[1].line)
+		}
+	}
+
+	// Without the backslash the newline stays in the string
+	tokens, _ := Tokenize("\"abc\ndef\" x")
+	if got := tokens[0].literal; got != (LoxString{value: "abc\ndef"}) {
+		t.Errorf("string with a newline = %q, want abc\\ndef", got)
+	}
+	if tokens[1].line != 2 {
+		t.Errorf("token after a string with a newline on line %d, want 2", tokens[1].line)
+	}
+
+	// A continuation goes with an escaped interpolation
+	expectOutput(t, "print \"\\${a}\\\n${1 + 1}\";", "${a}2\n")
+}
+
+func TestShebang(t *testing.T) {
+	expectTokens(t, "#!/usr/bin/env myinterpreter run\nprint 1;",
+		"PRINT print null", "NUMBER 1 1.0", "SEMICOLON ; null", "EOF  null")
+	expectOutput(t, "#!/usr/bin/env myinterpreter run\nprint 1;", "1\n")
+	// Lines still count from the shebang
+	if tokens, _ := Tokenize("#!lox\nx"); tokens[0].line != 2 {
+		t.Errorf("token after a shebang is on line %d, want 2", tokens[0].line)
+	}
+
+	for _, source := range []string{"print 1;\n#!not first", " #!indented"} {
+		if _, errors := Tokenize(source); len(errors) == 0 || errors[0].Message != "Unexpected character: #" {
+			t.Errorf("Tokenize(%q) errors = %v, want an unexpected #", source, errors)
+		}
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	tokens, errors := Tokenize("1 $\n\"open")
+	want := []ScanError{
+		{Line: 1, Offset: 2, Message: "Unexpected character: $"},
+		{Line: 2, Offset: 4, Message: "Unterminated string."},
+	}
+	if len(errors) != len(want) {
+		t.Fatalf("Tokenize errors = %v, want %v", errors, want)
+	}
+	for i := range want {
+		if errors[i] != want[i] {
+			t.Errorf("Tokenize error %d = %+v, want %+v", i, errors[i], want[i])
+		}
+	}
+	if last := tokens[len(tokens)-1]; last.Type() != EOF {
+		t.Errorf("Tokenize doesn't end with EOF, but %v", last.Type())
+	}
+}
+
+func TestTokenOffsets(t *testing.T) {
+	source := "var x = 1;\n  print \"a\nb\";\n"
+	tokens, errors := Tokenize(source)
+	if len(errors) > 0 {
+		t.Fatalf("Tokenize errors: %v", errors)
+	}
+	// As in jlox, a multi-line string is on the line where it ends
+	want := []struct {
+		lexeme       string
+		line, column int
+	}{
+		{"var", 1, 1}, {"x", 1, 5}, {"=", 1, 7}, {"1", 1, 9}, {";", 1, 10},
+		{"print", 2, 3}, {"\"a\nb\"", 3, 9}, {";", 3, 3}, {"", 4, 1},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("Tokenize gave %d tokens, want %d", len(tokens), len(want))
+	}
+	for i, tok := range tokens {
+		start, end := tok.Span()
+		if got := source[start:end]; got != want[i].lexeme || tok.Lexeme() != want[i].lexeme {
+			t.Errorf("token %d spans %q, lexeme %q, want %q", i, got, tok.Lexeme(), want[i].lexeme)
+		}
+		if tok.Line() != want[i].line || tok.Column() != want[i].column {
+			t.Errorf("token %d (%q) at %d:%d, want %d:%d", i, tok.Lexeme(), tok.Line(), tok.Column(), want[i].line, want[i].column)
+		}
+	}
+}
+
+// repeatedIdentifiers is a program using the same few identifiers and
+// keywords over and over
+var repeatedIdentifiers = strings.Repeat(`
+	var counter = 0;
+	fun increment(counter) { return counter + 1; }
+	while (counter < 10) { counter = increment(counter); print counter; }
+`, 500)
+
+// intern scans source like Tokenize, then makes identifiers and keywords
+// with the same text share one string through an intern map, the way a
+// scanner copying each lexeme out of the source would have to
+func intern(source string) []Token {
+	tokens, _ := Tokenize(source)
+	interned := make(map[string]string)
+	for i, tok := range tokens {
+		if _, keyword := keywords[tok.lexeme]; tok._type != IDENTIFIER && !keyword {
+			continue
+		}
+		lexeme, ok := interned[tok.lexeme]
+		if !ok {
+			lexeme = strings.Clone(tok.lexeme)
+			interned[lexeme] = lexeme
+		}
+		tokens[i].lexeme = lexeme
+	}
+	return tokens
+}
+
+func TestInternedTokens(t *testing.T) {
+	tokens, _ := Tokenize(repeatedIdentifiers)
+	interned := intern(repeatedIdentifiers)
+	if len(interned) != len(tokens) {
+		t.Fatalf("interning gave %d tokens, want %d", len(interned), len(tokens))
+	}
+	for i := range tokens {
+		if interned[i].String() != tokens[i].String() {
+			t.Fatalf("interned token %d = %s, want %s", i, interned[i].String(), tokens[i].String())
+		}
+	}
+}
+
+// BenchmarkLexemes compares lexemes sliced from the source, which share its
+// backing array and so cost no allocation each, against interning them
+func BenchmarkLexemes(b *testing.B) {
+	b.Run("substrings", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Tokenize(repeatedIdentifiers)
+		}
+	})
+	b.Run("interned", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			intern(repeatedIdentifiers)
+		}
+	})
+}
+
+func TestMaxErrors(t *testing.T) {
+	source := strings.Repeat("@", 500)
+	_, errors := Tokenize(source)
+	if len(errors) != DefaultMaxErrors+1 {
+		t.Fatalf("Tokenize reported %d errors, want %d", len(errors), DefaultMaxErrors+1)
+	}
+	if last := errors[len(errors)-1]; last.Message != "Too many errors." {
+		t.Errorf("last error is %q, want Too many errors.", last.Message)
+	}
+
+	scanner := NewScanner(source)
+	scanner.MaxErrors = 3
+	scanner.ScanTokens()
+	if len(scanner.errors) != 4 {
+		t.Errorf("scanner with MaxErrors 3 reported %d errors, want 4", len(scanner.errors))
+	}
+
+	scanner = NewScanner(source)
+	scanner.MaxErrors = 0
+	scanner.ScanTokens()
+	if len(scanner.errors) != 500 {
+		t.Errorf("scanner without a limit reported %d errors, want 500", len(scanner.errors))
+	}
+}
@@ -0,0 +1,263 @@
+package lox
+
+// Stats tallies the nodes of a syntax tree by kind, named after their types,
+// e.g. stats["Binary"] is the number of binary operations
+type Stats map[string]int
+
+// Count adds the nodes of every statement to the tally and returns it
+func (s Stats) Count(statements []Stmt) Stats {
+	s.stmts(statements)
+	return s
+}
+
+func (s Stats) expr(expr Expr) {
+	if expr != nil {
+		expr.Accept(s)
+	}
+}
+
+func (s Stats) exprs(exprs []Expr) {
+	for _, expr := range exprs {
+		s.expr(expr)
+	}
+}
+
+func (s Stats) stmt(stmt Stmt) {
+	if stmt != nil {
+		stmt.Accept(s)
+	}
+}
+
+func (s Stats) stmts(stmts []Stmt) {
+	for _, stmt := range stmts {
+		s.stmt(stmt)
+	}
+}
+
+func (s Stats) VisitLiteralExpr(literal *Literal) any {
+	s["Literal"]++
+	return nil
+}
+
+func (s Stats) VisitBinaryExpr(binary *Binary) any {
+	s["Binary"]++
+	s.expr(binary.Left)
+	s.expr(binary.Right)
+	return nil
+}
+
+func (s Stats) VisitInterpolationExpr(interpolation *Interpolation) any {
+	s["Interpolation"]++
+	s.exprs(interpolation.Parts)
+	return nil
+}
+
+func (s Stats) VisitGroupingExpr(grouping *Grouping) any {
+	s["Grouping"]++
+	s.expr(grouping.Expression)
+	return nil
+}
+
+func (s Stats) VisitUnaryExpr(unary *Unary) any {
+	s["Unary"]++
+	s.expr(unary.Right)
+	return nil
+}
+
+func (s Stats) VisitLogicalExpr(logical *Logical) any {
+	s["Logical"]++
+	s.expr(logical.Left)
+	s.expr(logical.Right)
+	return nil
+}
+
+func (s Stats) VisitVariableExpr(variable *Variable) any {
+	s["Variable"]++
+	return nil
+}
+
+func (s Stats) VisitAssignExpr(assign *Assign) any {
+	s["Assign"]++
+	s.expr(assign.Value)
+	return nil
+}
+
+func (s Stats) VisitCallExpr(call *Call) any {
+	s["Call"]++
+	s.expr(call.Callee)
+	s.exprs(call.Arguments)
+	return nil
+}
+
+func (s Stats) VisitConditionalExpr(conditional *Conditional) any {
+	s["Conditional"]++
+	s.expr(conditional.Condition)
+	s.expr(conditional.ThenBranch)
+	s.expr(conditional.ElseBranch)
+	return nil
+}
+
+func (s Stats) VisitGetExpr(get *Get) any {
+	s["Get"]++
+	s.expr(get.Object)
+	return nil
+}
+
+func (s Stats) VisitSetExpr(set *Set) any {
+	s["Set"]++
+	s.expr(set.Object)
+	s.expr(set.Value)
+	return nil
+}
+
+func (s Stats) VisitThisExpr(this *This) any {
+	s["This"]++
+	return nil
+}
+
+func (s Stats) VisitSuperExpr(super *Super) any {
+	s["Super"]++
+	return nil
+}
+
+func (s Stats) VisitFunctionExpr(function *FunctionExpr) any {
+	s["FunctionExpr"]++
+	s.stmts(function.Declaration.Body)
+	return nil
+}
+
+func (s Stats) VisitArrayLiteralExpr(array *ArrayLiteral) any {
+	s["ArrayLiteral"]++
+	s.exprs(array.Elements)
+	return nil
+}
+
+func (s Stats) VisitIndexExpr(index *Index) any {
+	s["Index"]++
+	s.expr(index.Object)
+	s.expr(index.Index)
+	return nil
+}
+
+func (s Stats) VisitIndexAssignExpr(assign *IndexAssign) any {
+	s["IndexAssign"]++
+	s.expr(assign.Object)
+	s.expr(assign.Index)
+	s.expr(assign.Value)
+	return nil
+}
+
+func (s Stats) VisitMapLiteralExpr(literal *MapLiteral) any {
+	s["MapLiteral"]++
+	s.exprs(literal.Keys)
+	s.exprs(literal.Values)
+	return nil
+}
+
+func (s Stats) VisitExpressionStmt(stmt *Expression) any {
+	s["Expression"]++
+	s.expr(stmt.Expression)
+	return nil
+}
+
+func (s Stats) VisitPrintStmt(stmt *Print) any {
+	s["Print"]++
+	s.expr(stmt.Expression)
+	return nil
+}
+
+func (s Stats) VisitVarStmt(stmt *Var) any {
+	s["Var"]++
+	s.expr(stmt.Initializer)
+	return nil
+}
+
+func (s Stats) VisitBlockStmt(stmt *Block) any {
+	s["Block"]++
+	s.stmts(stmt.Statements)
+	return nil
+}
+
+func (s Stats) VisitIfStmt(stmt *If) any {
+	s["If"]++
+	s.expr(stmt.Condition)
+	s.stmt(stmt.ThenBranch)
+	s.stmt(stmt.ElseBranch)
+	return nil
+}
+
+func (s Stats) VisitWhileStmt(stmt *While) any {
+	s["While"]++
+	s.expr(stmt.Condition)
+	s.stmt(stmt.Body)
+	s.expr(stmt.Increment)
+	return nil
+}
+
+func (s Stats) VisitFunctionStmt(stmt *Function) any {
+	s["Function"]++
+	s.stmts(stmt.Body)
+	return nil
+}
+
+func (s Stats) VisitReturnStmt(stmt *Return) any {
+	s["Return"]++
+	s.expr(stmt.Value)
+	return nil
+}
+
+func (s Stats) VisitClassStmt(stmt *Class) any {
+	s["Class"]++
+	if stmt.Superclass != nil {
+		s.expr(stmt.Superclass)
+	}
+	for _, method := range stmt.StaticMethods {
+		s.VisitFunctionStmt(method)
+	}
+	for _, method := range stmt.Methods {
+		s.VisitFunctionStmt(method)
+	}
+	return nil
+}
+
+func (s Stats) VisitBreakStmt(stmt *Break) any {
+	s["Break"]++
+	return nil
+}
+
+func (s Stats) VisitContinueStmt(stmt *Continue) any {
+	s["Continue"]++
+	return nil
+}
+
+func (s Stats) VisitSwitchStmt(stmt *Switch) any {
+	s["Switch"]++
+	s.expr(stmt.Subject)
+	for _, arm := range stmt.Cases {
+		s.expr(arm.Value)
+		s.stmts(arm.Body)
+	}
+	s.stmts(stmt.Default)
+	return nil
+}
+
+func (s Stats) VisitThrowStmt(stmt *Throw) any {
+	s["Throw"]++
+	s.expr(stmt.Value)
+	return nil
+}
+
+func (s Stats) VisitTryStmt(stmt *Try) any {
+	s["Try"]++
+	s.stmts(stmt.Body)
+	s.stmts(stmt.Catch)
+	s.stmts(stmt.Finally)
+	return nil
+}
+
+func (s Stats) VisitForEachStmt(stmt *ForEach) any {
+	s["ForEach"]++
+	s.expr(stmt.Collection)
+	s.stmt(stmt.Body)
+	return nil
+}
@@ -0,0 +1,26 @@
+package lox
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	statements, errors := parse(t, `
+		var a = 1 + 2 * 3;
+		fun f(x) { return x(a); }
+		if (a > 1) print f(clock);
+		class A { m() { return this; } }
+		for (var i = 0; i < 2; i = i + 1) {}`)
+	if len(errors) > 0 {
+		t.Fatalf("Parse errors: %v", errors)
+	}
+	want := Stats{
+		"Assign": 1, "Binary": 5, "Block": 2, "Call": 2, "Class": 1, "Function": 2,
+		"If": 1, "Literal": 7, "Print": 1, "Return": 2, "This": 1, "Var": 2,
+		"Variable": 7, "While": 1,
+	}
+	if got := (Stats{}).Count(statements); !maps.Equal(got, want) {
+		t.Errorf("Count = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,194 @@
+package lox
+
+// Stmt is the base interface for all statement types
+type Stmt interface {
+	Accept(visitor StmtVisitor) any
+}
+
+// StmtVisitor interface with methods for each statement type
+type StmtVisitor interface {
+	VisitExpressionStmt(stmt *Expression) any
+	VisitPrintStmt(stmt *Print) any
+	VisitVarStmt(stmt *Var) any
+	VisitBlockStmt(stmt *Block) any
+	VisitIfStmt(stmt *If) any
+	VisitWhileStmt(stmt *While) any
+	VisitFunctionStmt(stmt *Function) any
+	VisitReturnStmt(stmt *Return) any
+	VisitClassStmt(stmt *Class) any
+	VisitBreakStmt(stmt *Break) any
+	VisitContinueStmt(stmt *Continue) any
+	VisitSwitchStmt(stmt *Switch) any
+	VisitThrowStmt(stmt *Throw) any
+	VisitTryStmt(stmt *Try) any
+	VisitForEachStmt(stmt *ForEach) any
+}
+
+// Expression statement, an expression evaluated for its side effects
+type Expression struct {
+	Expression Expr
+}
+
+func (s *Expression) Accept(visitor StmtVisitor) any {
+	return visitor.VisitExpressionStmt(s)
+}
+
+// Print statement
+type Print struct {
+	Expression Expr
+}
+
+func (s *Print) Accept(visitor StmtVisitor) any {
+	return visitor.VisitPrintStmt(s)
+}
+
+// Var statement, Initializer is nil when there is none
+type Var struct {
+	Name        Token
+	Initializer Expr
+	Const       bool // Declared with const, so it can't be assigned
+}
+
+func (s *Var) Accept(visitor StmtVisitor) any {
+	return visitor.VisitVarStmt(s)
+}
+
+// Block statement, a list of statements in their own scope
+type Block struct {
+	Statements []Stmt
+}
+
+func (s *Block) Accept(visitor StmtVisitor) any {
+	return visitor.VisitBlockStmt(s)
+}
+
+// If statement, ElseBranch is nil when there is none
+type If struct {
+	Condition  Expr
+	ThenBranch Stmt
+	ElseBranch Stmt
+}
+
+func (s *If) Accept(visitor StmtVisitor) any {
+	return visitor.VisitIfStmt(s)
+}
+
+// While statement. For loops are desugared into these, keeping their
+// increment separate so that it still runs after a continue.
+type While struct {
+	Condition Expr
+	Body      Stmt
+	Increment Expr // nil for plain while loops
+	DoWhile   bool // The body runs once before the condition is checked
+}
+
+func (s *While) Accept(visitor StmtVisitor) any {
+	return visitor.VisitWhileStmt(s)
+}
+
+// Function declaration. Getters are methods declared without a parameter
+// list, which run when the property is accessed.
+type Function struct {
+	Name     Token
+	Params   []Token
+	Body     []Stmt
+	IsGetter bool
+}
+
+func (s *Function) Accept(visitor StmtVisitor) any {
+	return visitor.VisitFunctionStmt(s)
+}
+
+// Return statement, Value is nil for a bare "return;"
+type Return struct {
+	Keyword Token
+	Value   Expr
+}
+
+func (s *Return) Accept(visitor StmtVisitor) any {
+	return visitor.VisitReturnStmt(s)
+}
+
+// Class declaration
+type Class struct {
+	Name          Token
+	Superclass    *Variable // nil when the class doesn't inherit
+	Methods       []*Function
+	StaticMethods []*Function // Declared with a leading "class"
+}
+
+func (s *Class) Accept(visitor StmtVisitor) any {
+	return visitor.VisitClassStmt(s)
+}
+
+// Break statement, exits the innermost enclosing loop
+type Break struct {
+	Keyword Token
+}
+
+func (s *Break) Accept(visitor StmtVisitor) any {
+	return visitor.VisitBreakStmt(s)
+}
+
+// Continue statement, skips to the next iteration of the innermost loop
+type Continue struct {
+	Keyword Token
+}
+
+func (s *Continue) Accept(visitor StmtVisitor) any {
+	return visitor.VisitContinueStmt(s)
+}
+
+// Switch statement. Only the first case whose value equals the subject runs,
+// there is no fallthrough. Default is nil when there is none.
+type Switch struct {
+	Subject Expr
+	Cases   []SwitchCase
+	Default []Stmt
+}
+
+// SwitchCase is a single "case value:" arm of a switch
+type SwitchCase struct {
+	Value Expr
+	Body  []Stmt
+}
+
+func (s *Switch) Accept(visitor StmtVisitor) any {
+	return visitor.VisitSwitchStmt(s)
+}
+
+// Throw statement, raises Value as an exception
+type Throw struct {
+	Keyword Token
+	Value   Expr
+}
+
+func (s *Throw) Accept(visitor StmtVisitor) any {
+	return visitor.VisitThrowStmt(s)
+}
+
+// Try statement. Catch is nil when there is no catch clause and Finally is
+// nil when there is no finally clause, at least one of them is present.
+type Try struct {
+	Body      []Stmt
+	CatchName Token // Variable bound to the caught value
+	Catch     []Stmt
+	Finally   []Stmt
+}
+
+func (s *Try) Accept(visitor StmtVisitor) any {
+	return visitor.VisitTryStmt(s)
+}
+
+// ForEach statement, a for-in loop running Body once for each element of
+// an array or character of a string, with Name bound to it
+type ForEach struct {
+	Name       Token
+	In         Token
+	Collection Expr
+	Body       Stmt
+}
+
+func (s *ForEach) Accept(visitor StmtVisitor) any {
+	return visitor.VisitForEachStmt(s)
+}
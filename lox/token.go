@@ -0,0 +1,241 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"text/tabwriter"
+)
+
+type TokenType int
+
+const (
+	// Single-character tokens.
+	LEFT_PAREN TokenType = iota
+	RIGHT_PAREN
+	LEFT_BRACE
+	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
+	COMMA
+	DOT
+	MINUS
+	PLUS
+	SEMICOLON
+	SLASH
+	STAR
+	QUESTION
+	COLON
+	AMPERSAND
+	PIPE
+	CARET
+
+	// One or two character tokens.
+	BANG
+	BANG_EQUAL
+	EQUAL
+	EQUAL_EQUAL
+	GREATER
+	GREATER_EQUAL
+	LESS
+	LESS_EQUAL
+	STAR_STAR
+	PLUS_EQUAL
+	MINUS_EQUAL
+	STAR_EQUAL
+	SLASH_EQUAL
+	PLUS_PLUS
+	MINUS_MINUS
+	LESS_LESS
+	GREATER_GREATER
+
+	// Literals.
+	IDENTIFIER
+	STRING
+	INTERPOLATION // A string segment followed by a "${...}" expression
+	NUMBER
+
+	// Keywords.
+	AND
+	BREAK
+	CASE
+	CATCH
+	CLASS
+	CONST
+	CONTINUE
+	DEFAULT
+	DO
+	ELSE
+	FALSE
+	FINALLY
+	FUN
+	FOR
+	IF
+	IN
+	NIL
+	OR
+	PRINT
+	RETURN
+	SUPER
+	SWITCH
+	THIS
+	THROW
+	TRUE
+	TRY
+	VAR
+	WHILE
+
+	// Trivia, only scanned when the Scanner keeps comments
+	COMMENT
+
+	// EOF token
+	EOF
+)
+
+var keywords = map[string]TokenType{
+	"and":      AND,
+	"break":    BREAK,
+	"case":     CASE,
+	"catch":    CATCH,
+	"class":    CLASS,
+	"const":    CONST,
+	"continue": CONTINUE,
+	"default":  DEFAULT,
+	"do":       DO,
+	"else":     ELSE,
+	"false":    FALSE,
+	"finally":  FINALLY,
+	"for":      FOR,
+	"fun":      FUN,
+	"if":       IF,
+	"in":       IN,
+	"nil":      NIL,
+	"or":       OR,
+	"print":    PRINT,
+	"return":   RETURN,
+	"super":    SUPER,
+	"switch":   SWITCH,
+	"this":     THIS,
+	"throw":    THROW,
+	"true":     TRUE,
+	"try":      TRY,
+	"var":      VAR,
+	"while":    WHILE,
+}
+
+type LoxLiteral interface {
+	RawPrint() string
+}
+
+type LoxString struct {
+	value string
+}
+
+func (s LoxString) RawPrint() string {
+	return fmt.Sprintf("%s", s.value)
+}
+
+type LoxNumber struct {
+	value float64
+}
+
+// RawPrint renders the number in plain decimal, with ".0" on whole numbers,
+// however the literal was written: 0xFF and 2.55e2 are both 255.0
+func (n LoxNumber) RawPrint() string {
+	text := strconv.FormatFloat(n.value, 'f', -1, 64)
+	if n.value == math.Trunc(n.value) && !math.IsInf(n.value, 0) {
+		text += ".0"
+	}
+	return text
+}
+
+type LoxBoolean struct {
+	value bool
+}
+
+func (b LoxBoolean) RawPrint() string {
+	return strconv.FormatBool(b.value)
+}
+
+type LoxNil struct{}
+
+func (n LoxNil) RawPrint() string {
+	return "nil"
+}
+
+type LoxEmptyLiteral struct{}
+
+func (e LoxEmptyLiteral) RawPrint() string {
+	return "null"
+}
+
+type Token struct {
+	_type   TokenType
+	lexeme  string
+	literal LoxLiteral
+	line    int
+	column  int // 1-based column, in runes, of the token's first character
+	// Byte offsets of the lexeme in the source, end exclusive
+	startOffset int
+	endOffset   int
+}
+
+func (tok *Token) String() string {
+	return fmt.Sprintf("%s %s %s", tok._type, tok.lexeme, tok.literal.RawPrint())
+}
+
+// Type is the kind of token
+func (tok Token) Type() TokenType {
+	return tok._type
+}
+
+// Lexeme is the token's text as it appears in the source
+func (tok Token) Lexeme() string {
+	return tok.lexeme
+}
+
+// Literal is the value of a NUMBER, STRING or INTERPOLATION token as a
+// float64 or string, or nil for any other token
+func (tok Token) Literal() any {
+	switch literal := tok.literal.(type) {
+	case LoxNumber:
+		return literal.value
+	case LoxString:
+		return literal.value
+	}
+	return nil
+}
+
+// Line is the 1-based line the token ends on
+func (tok Token) Line() int {
+	return tok.line
+}
+
+// Column is the 1-based column, in runes, of the token's first character
+func (tok Token) Column() int {
+	return tok.column
+}
+
+// Span is the byte offsets of the lexeme in the source, end exclusive
+func (tok Token) Span() (start, end int) {
+	return tok.startOffset, tok.endOffset
+}
+
+// WriteTokenTable writes tokens to w one per row, in aligned columns: type,
+// lexeme, literal, line, column, and the start and end byte offsets of the
+// lexeme. Lexemes and string literals are quoted so each row stays on one
+// line.
+func WriteTokenTable(w io.Writer, tokens []Token) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TYPE\tLEXEME\tLITERAL\tLINE\tCOL\tSTART\tEND")
+	for _, tok := range tokens {
+		literal := tok.literal.RawPrint()
+		if _, ok := tok.literal.(LoxString); ok {
+			literal = strconv.Quote(literal)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%d\t%d\t%d\n", tok._type, strconv.Quote(tok.lexeme),
+			literal, tok.line, tok.column, tok.startOffset, tok.endOffset)
+	}
+	return table.Flush()
+}
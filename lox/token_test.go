@@ -0,0 +1,47 @@
+package lox
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTokenTable compares the token table of each testdata/tokens/*.lox
+// program with the .txt file beside it. Run with -update to rewrite the .txt
+// files.
+func TestTokenTable(t *testing.T) {
+	programs, err := filepath.Glob(filepath.Join("testdata", "tokens", "*.lox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, program := range programs {
+		name := strings.TrimSuffix(filepath.Base(program), ".lox")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens, errors := Tokenize(string(source))
+			if len(errors) > 0 {
+				t.Fatalf("Tokenize errors: %v", errors)
+			}
+			var table strings.Builder
+			if err := WriteTokenTable(&table, tokens); err != nil {
+				t.Fatal(err)
+			}
+			got := table.String()
+
+			goldenPath := strings.TrimSuffix(program, ".lox") + ".txt"
+			if *update {
+				if err := os.WriteFile(goldenPath, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			} else if want, err := os.ReadFile(goldenPath); err != nil {
+				t.Fatal(err)
+			} else if got != string(want) {
+				t.Errorf("WriteTokenTable(%s) =\n%s\nwant:\n%s", program, got, want)
+			}
+		})
+	}
+}